@@ -0,0 +1,153 @@
+// Package bench times the bench blocks of a script, for golox bench.
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"golox/interpreter"
+	"golox/stmt"
+	"io"
+	"os"
+	"runtime"
+	"time"
+)
+
+// the most iterations a benchmark is measured with, whatever its speed
+const maxIterations = 1_000_000_000
+
+// Config is how long the benchmarks run.
+type Config struct {
+	// Warmup is the number of untimed runs before the measured ones
+	Warmup int
+	// Time is the least time the measured runs take together
+	Time time.Duration
+}
+
+// Result is the measurement of a benchmark, the baseline files hold a list of them.
+type Result struct {
+	File        string  `json:"file"`
+	Name        string  `json:"name"`
+	Iterations  int     `json:"iterations"`
+	NsPerOp     float64 `json:"ns_per_op"`
+	AllocsPerOp float64 `json:"allocs_per_op"`
+	BytesPerOp  float64 `json:"bytes_per_op"`
+}
+
+// Run runs the script in file, then measures each of its bench blocks in the state it left.
+// What the script and the blocks print is discarded.
+func Run(file string, statements []stmt.Statement[any], config Config) ([]Result, error) {
+	interp := interpreter.NewInterpreter()
+	interp.Out = io.Discard
+
+	if err := interp.Run(statements); err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	for _, st := range statements {
+		b, ok := st.(*stmt.BenchStmt[any])
+		if !ok {
+			continue
+		}
+
+		result, err := measure(interp, b, config)
+		if err != nil {
+			return results, fmt.Errorf("bench %q: %w", b.Name, err)
+		}
+		result.File = file
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// measure runs a benchmark with more and more iterations until they take the configured time.
+func measure(interp *interpreter.Interpreter, b *stmt.BenchStmt[any], config Config) (Result, error) {
+	body := []stmt.Statement[any]{b.Body}
+
+	for range config.Warmup {
+		if err := interp.Run(body); err != nil {
+			return Result{}, err
+		}
+	}
+
+	n := 1
+	for {
+		runtime.GC()
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+
+		start := time.Now()
+		for range n {
+			if err := interp.Run(body); err != nil {
+				return Result{}, err
+			}
+		}
+		elapsed := time.Since(start)
+		runtime.ReadMemStats(&after)
+
+		if elapsed >= config.Time || n >= maxIterations {
+			return Result{
+				Name:        b.Name,
+				Iterations:  n,
+				NsPerOp:     float64(elapsed.Nanoseconds()) / float64(n),
+				AllocsPerOp: float64(after.Mallocs-before.Mallocs) / float64(n),
+				BytesPerOp:  float64(after.TotalAlloc-before.TotalAlloc) / float64(n),
+			}, nil
+		}
+
+		// aim past the configured time from the last speed, growing at most a hundredfold like go test
+		next := int(1.2 * float64(n) * float64(config.Time) / float64(max(elapsed, 1)))
+		n = min(max(next, n+1), 100*n, maxIterations)
+	}
+}
+
+// Load reads a baseline written by Save.
+func Load(path string) ([]Result, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return results, nil
+}
+
+// Save writes results as a baseline for later runs.
+func Save(path string, results []Result) error {
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Write prints a line per result, followed by its change from the baseline
+// result of the same file and name when there is one.
+func Write(w io.Writer, results, baseline []Result) {
+	for _, r := range results {
+		fmt.Fprintf(w, "%s: %-30s %10d %14.1f ns/op %10.1f allocs/op %12.1f B/op",
+			r.File, r.Name, r.Iterations, r.NsPerOp, r.AllocsPerOp, r.BytesPerOp)
+
+		for _, old := range baseline {
+			if old.File == r.File && old.Name == r.Name {
+				fmt.Fprintf(w, "  (%s ns/op, %s allocs/op)", change(old.NsPerOp, r.NsPerOp), change(old.AllocsPerOp, r.AllocsPerOp))
+				break
+			}
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// change formats the relative change from the baseline value to the current one.
+func change(baseline, current float64) string {
+	if baseline == 0 {
+		if current == 0 {
+			return "~"
+		}
+		return "+inf%"
+	}
+	return fmt.Sprintf("%+.1f%%", (current-baseline)/baseline*100)
+}
@@ -0,0 +1,66 @@
+package bench
+
+import (
+	"golox/parser"
+	"golox/scanner"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const program = `var limit = 10;
+print "setup";
+
+bench "loop" {
+  for (var i = 0; i < limit; i = i + 1) {}
+}
+
+bench "fails" {
+  assert limit == 0;
+}
+`
+
+func TestRun(t *testing.T) {
+	statements, errs := parser.NewParser(scanner.NewScanner(program).ScanTokens()).Parse()
+	if errs != nil {
+		t.Fatalf("parse errors: %v", errs)
+	}
+
+	results, err := Run("bench.lox", statements, Config{Warmup: 2, Time: time.Millisecond})
+	if err == nil || !strings.Contains(err.Error(), `bench "fails": [line 9] RuntimeError: Assertion failed`) {
+		t.Errorf("got error %v", err)
+	}
+
+	if len(results) != 1 {
+		t.Fatalf("got results %+v, want the loop benchmark only", results)
+	}
+	r := results[0]
+	if r.File != "bench.lox" || r.Name != "loop" || r.Iterations < 1 || r.NsPerOp <= 0 {
+		t.Errorf("got result %+v", r)
+	}
+}
+
+func TestBaseline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	results := []Result{{File: "a.lox", Name: "loop", Iterations: 10, NsPerOp: 200, AllocsPerOp: 4, BytesPerOp: 64}}
+	if err := Save(path, results); err != nil {
+		t.Fatal(err)
+	}
+
+	baseline, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(baseline, results) {
+		t.Errorf("loaded %+v, want %+v", baseline, results)
+	}
+
+	var out strings.Builder
+	current := []Result{{File: "a.lox", Name: "loop", Iterations: 20, NsPerOp: 150, AllocsPerOp: 4, BytesPerOp: 64}}
+	Write(&out, current, baseline)
+	if want := "(-25.0% ns/op, +0.0% allocs/op)"; !strings.Contains(out.String(), want) {
+		t.Errorf("got %q, want the change %q", out.String(), want)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"golox/tools"
+	"os"
+)
+
+// golox ast [--tree | --json] <script>: print the parsed syntax tree without running it.
+func astCommand(args []string) {
+	flags := flag.NewFlagSet("ast", flag.ExitOnError)
+	tree := flags.Bool("tree", false, "print an indented tree instead of S-expressions")
+	asJSON := flags.Bool("json", false, "print the tree as JSON")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: golox ast [--tree | --json] <script>")
+		os.Exit(64)
+	}
+
+	statements, ok := parseFile(flags.Arg(0))
+	if !ok {
+		os.Exit(65)
+	}
+
+	switch {
+	case *asJSON:
+		data, err := tools.EncodeJSON(statements)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error encoding the tree: %v\n", err)
+			os.Exit(70)
+		}
+		fmt.Println(string(data))
+	case *tree:
+		printer := &tools.TreePrinter{}
+		fmt.Print(printer.PrintProgram(statements))
+	default:
+		printer := &tools.AstPrinter{}
+		fmt.Print(printer.PrintProgram(statements))
+	}
+}
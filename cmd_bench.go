@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"golox/bench"
+	"golox/loxtest"
+	"os"
+	"time"
+)
+
+// golox bench [--warmup n] [--benchtime d] [--baseline file] [--save file] <script|dir>...:
+// time the bench blocks of the scripts, directories stand for every script below them.
+func benchCommand(args []string) {
+	flags := flag.NewFlagSet("bench", flag.ExitOnError)
+	warmup := flags.Int("warmup", 10, "the number of untimed runs of each benchmark")
+	benchtime := flags.Duration("benchtime", time.Second, "the least time each benchmark is measured for")
+	baseline := flags.String("baseline", "", "compare the results with the baseline saved in `file`")
+	save := flags.String("save", "", "save the results as a baseline to `file`")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: golox bench [--warmup n] [--benchtime d] [--baseline file] [--save file] <script|dir>...")
+		os.Exit(64)
+	}
+
+	var previous []bench.Result
+	if *baseline != "" {
+		var err error
+		if previous, err = bench.Load(*baseline); err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading the baseline: %v\n", err)
+			os.Exit(66)
+		}
+	}
+
+	files, err := loxtest.Files(flags.Args()...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(66)
+	}
+
+	config := bench.Config{Warmup: *warmup, Time: *benchtime}
+	var results []bench.Result
+	for _, path := range files {
+		statements, ok := parseFile(path)
+		if !ok {
+			os.Exit(65)
+		}
+
+		fileResults, err := bench.Run(path, statements, config)
+		bench.Write(os.Stdout, fileResults, previous)
+		results = append(results, fileResults...)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			os.Exit(70)
+		}
+	}
+
+	if *save != "" {
+		if err := bench.Save(*save, results); err != nil {
+			fmt.Fprintf(os.Stderr, "Error saving the baseline: %v\n", err)
+			os.Exit(74)
+		}
+	}
+}
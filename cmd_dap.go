@@ -0,0 +1,20 @@
+package main
+
+import (
+	"fmt"
+	"golox/dap"
+	"os"
+)
+
+// golox dap: serve the debug adapter protocol over stdin and stdout.
+func dapCommand(args []string) {
+	if len(args) != 0 {
+		fmt.Fprintln(os.Stderr, "Usage: golox dap")
+		os.Exit(64)
+	}
+
+	if err := dap.NewServer(os.Stdin, os.Stdout).Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "dap: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"fmt"
+	"golox/debugger"
+	"os"
+)
+
+// golox debug <script>: run a script under the command line debugger.
+func debugCommand(args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: golox debug <script>")
+		os.Exit(64)
+	}
+
+	source := readSource(args[0])
+	statements, ok := parseSource(source)
+	if !ok {
+		os.Exit(65)
+	}
+
+	debugger.New(source, statements, os.Stdin, os.Stdout).Run()
+}
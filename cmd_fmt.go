@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"golox/format"
+	"os"
+)
+
+// golox fmt [--check | --write] <script>...: reformat scripts in the canonical style.
+func fmtCommand(args []string) {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	check := flags.Bool("check", false, "list the scripts that aren't formatted and exit with status 1 if any")
+	write := flags.Bool("write", false, "rewrite the scripts in place instead of printing them")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: golox fmt [--check | --write] <script>...")
+		os.Exit(64)
+	}
+
+	unformatted := false
+	for _, path := range flags.Args() {
+		source := readSource(path)
+
+		formatted, err := format.Source(source)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			os.Exit(65)
+		}
+
+		switch {
+		case *check:
+			if formatted != source {
+				fmt.Println(path)
+				unformatted = true
+			}
+		case *write:
+			if formatted != source {
+				if err := os.WriteFile(path, []byte(formatted), 0644); err != nil {
+					fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
+					os.Exit(74)
+				}
+			}
+		default:
+			fmt.Print(formatted)
+		}
+	}
+
+	if unformatted {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"golox/lint"
+	"os"
+	"slices"
+	"strings"
+)
+
+// golox lint [--disable rule,...] <script>...: report suspicious code without running it.
+func lintCommand(args []string) {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	disable := flags.String("disable", "", "comma separated rules to skip")
+	list := flags.Bool("list", false, "list the available rules")
+	flags.Parse(args)
+
+	if *list {
+		for _, rule := range lint.Rules {
+			fmt.Printf("%-20s %s\n", rule.Name, rule.Description)
+		}
+		return
+	}
+
+	if flags.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: golox lint [--disable rule,...] [--list] <script>...")
+		os.Exit(64)
+	}
+
+	config := lint.Config{Disabled: map[string]bool{}}
+	for _, name := range strings.Split(*disable, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		if !slices.ContainsFunc(lint.Rules, func(rule lint.Rule) bool { return rule.Name == name }) {
+			fmt.Fprintf(os.Stderr, "Unknown rule %q, see golox lint --list.\n", name)
+			os.Exit(64)
+		}
+		config.Disabled[name] = true
+	}
+
+	failed, findings := false, false
+	for _, path := range flags.Args() {
+		diagnostics, err := lint.Source(readSource(path), config)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			failed = true
+			continue
+		}
+
+		for _, d := range diagnostics {
+			fmt.Printf("%s: %s\n", path, d)
+			findings = true
+		}
+	}
+
+	// errors take precedence over warnings
+	if failed {
+		os.Exit(65)
+	}
+	if findings {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"fmt"
+	"golox/lsp"
+	"os"
+)
+
+// golox lsp: serve the language server protocol over stdin and stdout.
+func lspCommand(args []string) {
+	if len(args) != 0 {
+		fmt.Fprintln(os.Stderr, "Usage: golox lsp")
+		os.Exit(64)
+	}
+
+	if err := lsp.NewServer(os.Stdin, os.Stdout).Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "lsp: %v\n", err)
+		os.Exit(1)
+	}
+}
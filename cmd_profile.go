@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"golox/profiler"
+	"os"
+)
+
+// profileFile runs a script under the profiler, prints the top lines and
+// functions on stderr and writes the pprof profile to output.
+func profileFile(path, output string, top int) {
+	p := profiler.New(path)
+	interp.Hook = p
+
+	p.Start()
+	run(readSource(path))
+	p.Stop()
+
+	p.WriteSummary(os.Stderr, top)
+
+	file, err := os.Create(output)
+	if err == nil {
+		err = p.WriteProfile(file)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing the profile: %v\n", err)
+		os.Exit(74)
+	}
+
+	exitOnError()
+}
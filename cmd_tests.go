@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"golox/coverage"
+	"golox/interpreter"
+	"golox/loxtest"
+	"golox/stmt"
+	"os"
+)
+
+// golox test [--cover] [--lcov file] [--html file] <script|dir>...: run scripts against their
+// "// expect:" annotations and run their test blocks, directories stand for every script below them.
+func testCommand(args []string) {
+	flags := flag.NewFlagSet("test", flag.ExitOnError)
+	cover := flags.Bool("cover", false, "report the statement and branch coverage")
+	lcov := flags.String("lcov", "", "write the coverage as an LCOV tracefile to `file`, implies --cover")
+	htmlReport := flags.String("html", "", "write an annotated HTML coverage report to `file`, implies --cover")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: golox test [--cover] [--lcov file] [--html file] <script|dir>...")
+		os.Exit(64)
+	}
+	*cover = *cover || *lcov != "" || *htmlReport != ""
+
+	files, err := loxtest.Files(flags.Args()...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(66)
+	}
+
+	passed, failed := 0, 0
+	testsPassed, testsFailed := 0, 0
+	var profiles []*coverage.Profile
+	for _, path := range files {
+		source := readSource(path)
+
+		var hook func(statements []stmt.Statement[any]) interpreter.Hook
+		if *cover {
+			hook = func(statements []stmt.Statement[any]) interpreter.Hook {
+				profile := coverage.New(path, source, statements)
+				profiles = append(profiles, profile)
+				return profile
+			}
+		}
+
+		result := loxtest.Run(source, hook)
+		testsPassed += result.Passed()
+		testsFailed += len(result.Tests) - result.Passed()
+
+		failures := loxtest.Expect(source).Check(result)
+		if len(failures) == 0 {
+			fmt.Printf("ok   %s\n", path)
+			passed++
+			continue
+		}
+
+		fmt.Printf("FAIL %s\n", path)
+		for _, failure := range failures {
+			fmt.Printf("    %s\n", failure)
+		}
+		failed++
+	}
+
+	fmt.Printf("\nscripts: %d passed, %d failed\n", passed, failed)
+	if testsPassed+testsFailed > 0 {
+		fmt.Printf("tests: %d passed, %d failed\n", testsPassed, testsFailed)
+	}
+
+	if *cover {
+		reportCoverage(profiles, *lcov, *htmlReport)
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func reportCoverage(profiles []*coverage.Profile, lcov, htmlReport string) {
+	var total coverage.Summary
+	for _, profile := range profiles {
+		summary := profile.Summary()
+		total = total.Add(summary)
+		fmt.Printf("%s: %s\n", profile.Path, summary)
+	}
+	if len(profiles) > 1 {
+		fmt.Printf("total: %s\n", total)
+	}
+
+	write := func(path string, report func(*os.File) error) {
+		if path == "" {
+			return
+		}
+
+		file, err := os.Create(path)
+		if err == nil {
+			err = report(file)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", path, err)
+			os.Exit(74)
+		}
+	}
+
+	write(lcov, func(file *os.File) error { return coverage.WriteLCOV(file, profiles) })
+	write(htmlReport, func(file *os.File) error { return coverage.WriteHTML(file, profiles) })
+}
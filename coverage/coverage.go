@@ -0,0 +1,277 @@
+// Package coverage records the statements and branches a program executes.
+package coverage
+
+import (
+	"golox/expr"
+	"golox/interpreter"
+	"golox/stmt"
+)
+
+// Statement is a statement of the program, blocks aside since they only group others.
+type Statement struct {
+	Line  int
+	Count int
+}
+
+// Branch is a point where execution goes one of two ways: the then and else arms
+// of an if statement or a conditional expression, or the short-circuit and the
+// right operand of a logical expression.
+type Branch struct {
+	Line int
+	// Kind is "if", "?", "and", "or" or "??"
+	Kind string
+	// Taken counts the executions of each way, the then arm or the right operand first
+	Taken [2]int
+}
+
+// Profile is the coverage of one script, it is the interpreter hook recording it.
+type Profile struct {
+	Path   string
+	Source string
+
+	Statements []*Statement
+	Branches   []*Branch
+
+	statements   map[stmt.Statement[any]]*Statement
+	ifs          map[*stmt.IfStmt[any]]*Branch
+	logicals     map[*expr.Logical[any]]*Branch
+	conditionals map[*expr.Conditional[any]]*Branch
+}
+
+// New returns an empty profile listing every statement and branch of the program.
+func New(path, source string, statements []stmt.Statement[any]) *Profile {
+	p := &Profile{
+		Path:         path,
+		Source:       source,
+		statements:   map[stmt.Statement[any]]*Statement{},
+		ifs:          map[*stmt.IfStmt[any]]*Branch{},
+		logicals:     map[*expr.Logical[any]]*Branch{},
+		conditionals: map[*expr.Conditional[any]]*Branch{},
+	}
+
+	c := &collector{profile: p}
+	for _, st := range statements {
+		c.stmt(st)
+	}
+	return p
+}
+
+func (p *Profile) BeforeExecute(i *interpreter.Interpreter, st stmt.Statement[any]) {
+	if s, ok := p.statements[st]; ok {
+		s.Count++
+	}
+}
+
+func (p *Profile) IfBranch(i *interpreter.Interpreter, st *stmt.IfStmt[any], then bool) {
+	p.take(p.ifs[st], then)
+}
+
+func (p *Profile) LogicalBranch(i *interpreter.Interpreter, e *expr.Logical[any], right bool) {
+	p.take(p.logicals[e], right)
+}
+
+func (p *Profile) ConditionalBranch(i *interpreter.Interpreter, e *expr.Conditional[any], then bool) {
+	p.take(p.conditionals[e], then)
+}
+
+func (p *Profile) take(b *Branch, first bool) {
+	if b == nil {
+		return
+	}
+	if first {
+		b.Taken[0]++
+	} else {
+		b.Taken[1]++
+	}
+}
+
+// Summary counts the statements and branch ways, both the covered ones and all of them.
+type Summary struct {
+	Statements, CoveredStatements int
+	Branches, CoveredBranches     int
+}
+
+func (p *Profile) Summary() Summary {
+	var s Summary
+	for _, st := range p.Statements {
+		s.Statements++
+		if st.Count > 0 {
+			s.CoveredStatements++
+		}
+	}
+	for _, b := range p.Branches {
+		for _, taken := range b.Taken {
+			s.Branches++
+			if taken > 0 {
+				s.CoveredBranches++
+			}
+		}
+	}
+	return s
+}
+
+// Add returns the sum of two summaries.
+func (s Summary) Add(other Summary) Summary {
+	return Summary{
+		Statements:        s.Statements + other.Statements,
+		CoveredStatements: s.CoveredStatements + other.CoveredStatements,
+		Branches:          s.Branches + other.Branches,
+		CoveredBranches:   s.CoveredBranches + other.CoveredBranches,
+	}
+}
+
+// percent is 100 when there is nothing to cover.
+func percent(covered, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return 100 * float64(covered) / float64(total)
+}
+
+func (s Summary) StatementPercent() float64 {
+	return percent(s.CoveredStatements, s.Statements)
+}
+
+func (s Summary) BranchPercent() float64 {
+	return percent(s.CoveredBranches, s.Branches)
+}
+
+// collector walks the program to list its statements and branches.
+type collector struct {
+	profile *Profile
+}
+
+func (c *collector) stmt(st stmt.Statement[any]) {
+	if st == nil {
+		return
+	}
+
+	if _, ok := st.(*stmt.BlockStmt[any]); !ok {
+		line, _ := stmt.Lines(st)
+		s := &Statement{Line: line}
+		c.profile.statements[st] = s
+		c.profile.Statements = append(c.profile.Statements, s)
+	}
+	st.Accept(c)
+}
+
+func (c *collector) expr(e expr.Expression[any]) {
+	if e != nil {
+		e.Accept(c)
+	}
+}
+
+func (c *collector) VisitExpressionStmt(es *stmt.ExpressionStmt[any]) {
+	c.expr(es.Expr)
+}
+
+func (c *collector) VisitPrintStmt(ps *stmt.PrintStmt[any]) {
+	c.expr(ps.Expr)
+}
+
+func (c *collector) VisitVarStmt(v *stmt.VarStmt[any]) {
+	c.expr(v.Initializer)
+}
+
+func (c *collector) VisitBlockStmt(b *stmt.BlockStmt[any]) {
+	for _, st := range b.Stmts {
+		c.stmt(st)
+	}
+}
+
+func (c *collector) VisitIfStmt(i *stmt.IfStmt[any]) {
+	b := &Branch{Line: i.Keyword.Line, Kind: "if"}
+	c.profile.ifs[i] = b
+	c.profile.Branches = append(c.profile.Branches, b)
+
+	c.expr(i.Condition)
+	c.stmt(i.ThenBranch)
+	c.stmt(i.ElseBranch)
+}
+
+func (c *collector) VisitWhileStmt(w *stmt.WhileStmt[any]) {
+	c.expr(w.Condition)
+	c.stmt(w.Body)
+}
+
+func (c *collector) VisitTestStmt(t *stmt.TestStmt[any]) {
+	c.stmt(t.Body)
+}
+
+func (c *collector) VisitBenchStmt(b *stmt.BenchStmt[any]) {
+	c.stmt(b.Body)
+}
+
+func (c *collector) VisitAssertStmt(a *stmt.AssertStmt[any]) {
+	c.expr(a.Expr)
+}
+
+func (c *collector) VisitBinary(b *expr.Binary[any]) any {
+	c.expr(b.Left)
+	c.expr(b.Right)
+	return nil
+}
+
+func (c *collector) VisitUnary(u *expr.Unary[any]) any {
+	c.expr(u.Right)
+	return nil
+}
+
+func (c *collector) VisitGrouping(g *expr.Grouping[any]) any {
+	c.expr(g.Expression)
+	return nil
+}
+
+func (c *collector) VisitLiteral(l *expr.Literal[any]) any {
+	return nil
+}
+
+func (c *collector) VisitVariable(v *expr.Variable[any]) any {
+	return nil
+}
+
+func (c *collector) VisitAssignment(a *expr.Assignment[any]) any {
+	c.expr(a.Exp)
+	return nil
+}
+
+func (c *collector) VisitCompoundAssignment(a *expr.CompoundAssignment[any]) any {
+	c.expr(a.Value)
+	return nil
+}
+
+func (c *collector) VisitConditional(e *expr.Conditional[any]) any {
+	b := &Branch{Line: e.Question.Line, Kind: e.Question.Lexeme}
+	c.profile.conditionals[e] = b
+	c.profile.Branches = append(c.profile.Branches, b)
+
+	c.expr(e.Condition)
+	c.expr(e.Then)
+	c.expr(e.Else)
+	return nil
+}
+
+func (c *collector) VisitLogical(l *expr.Logical[any]) any {
+	b := &Branch{Line: l.Operator.Line, Kind: l.Operator.Lexeme}
+	c.profile.logicals[l] = b
+	c.profile.Branches = append(c.profile.Branches, b)
+
+	c.expr(l.Left)
+	c.expr(l.Right)
+	return nil
+}
+
+func (c *collector) VisitCall(call *expr.Call[any]) any {
+	c.expr(call.Calleee)
+	for _, argument := range call.Arguments {
+		c.expr(argument)
+	}
+	return nil
+}
+
+func (c *collector) VisitInterpolation(interpolation *expr.Interpolation[any]) any {
+	for _, part := range interpolation.Parts {
+		c.expr(part)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package coverage
+
+import (
+	"golox/interpreter"
+	"golox/parser"
+	"golox/scanner"
+	"io"
+	"strings"
+	"testing"
+)
+
+func run(t *testing.T, source string) *Profile {
+	t.Helper()
+
+	statements, errs := parser.NewParser(scanner.NewScanner(source).ScanTokens()).Parse()
+	if errs != nil {
+		t.Fatalf("parse errors: %v", errs)
+	}
+
+	p := New("test.lox", source, statements)
+	interp := interpreter.NewInterpreter()
+	interp.Out = io.Discard
+	interp.Hook = p
+	interp.Interpret(statements)
+	return p
+}
+
+const program = `var a = 1;
+if (a > 2) {
+  print "big";
+} else {
+  print "small";
+}
+print a == 1 or a == 2;
+print false and clock();
+`
+
+func TestSummary(t *testing.T) {
+	summary := run(t, program).Summary()
+
+	want := Summary{Statements: 6, CoveredStatements: 5, Branches: 6, CoveredBranches: 3}
+	if summary != want {
+		t.Errorf("got %+v, want %+v", summary, want)
+	}
+	if got := summary.String(); got != "statements 83.3% (5/6), branches 50.0% (3/6)" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestBranches(t *testing.T) {
+	p := run(t, program)
+
+	want := []Branch{
+		{Line: 2, Kind: "if", Taken: [2]int{0, 1}},
+		{Line: 7, Kind: "or", Taken: [2]int{0, 1}},
+		{Line: 8, Kind: "and", Taken: [2]int{0, 1}},
+	}
+	for i, b := range p.Branches {
+		if *b != want[i] {
+			t.Errorf("branch %d: got %+v, want %+v", i, *b, want[i])
+		}
+	}
+}
+
+func TestConditionalBranches(t *testing.T) {
+	p := run(t, "var a = nil;\nprint a ?? 1;\nprint a != nil ? a : 2;\n")
+
+	want := []Branch{
+		{Line: 2, Kind: "??", Taken: [2]int{1, 0}},
+		{Line: 3, Kind: "?", Taken: [2]int{0, 1}},
+	}
+	if len(p.Branches) != len(want) {
+		t.Fatalf("got %d branches, want %d", len(p.Branches), len(want))
+	}
+	for i, b := range p.Branches {
+		if *b != want[i] {
+			t.Errorf("branch %d: got %+v, want %+v", i, *b, want[i])
+		}
+	}
+}
+
+func TestLCOV(t *testing.T) {
+	var out strings.Builder
+	if err := WriteLCOV(&out, []*Profile{run(t, program)}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `TN:
+SF:test.lox
+DA:1,1
+DA:2,1
+DA:3,0
+DA:5,1
+DA:7,1
+DA:8,1
+LF:6
+LH:5
+BRDA:2,0,0,0
+BRDA:2,0,1,1
+BRDA:7,1,0,0
+BRDA:7,1,1,1
+BRDA:8,2,0,0
+BRDA:8,2,1,1
+BRF:6
+BRH:3
+end_of_record
+`
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestHTML(t *testing.T) {
+	var out strings.Builder
+	if err := WriteHTML(&out, []*Profile{run(t, program)}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		`<span class="line partial"><span class="count">1</span><span class="number">2</span>if (a &gt; 2) {</span>`,
+		`<span class="line uncovered"><span class="count">0</span><span class="number">3</span>  print &#34;big&#34;;</span>`,
+		`<span class="line"><span class="count"></span><span class="number">4</span>} else {</span>`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("missing %s", want)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package coverage
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+func (s Summary) String() string {
+	return fmt.Sprintf("statements %.1f%% (%d/%d), branches %.1f%% (%d/%d)",
+		s.StatementPercent(), s.CoveredStatements, s.Statements,
+		s.BranchPercent(), s.CoveredBranches, s.Branches)
+}
+
+// lineCounts returns the execution count of every line holding the start of a
+// statement, the most executed statement of the line counts.
+func (p *Profile) lineCounts() map[int]int {
+	counts := map[int]int{}
+	for _, st := range p.Statements {
+		if st.Line == 0 {
+			continue
+		}
+		if count, ok := counts[st.Line]; !ok || st.Count > count {
+			counts[st.Line] = st.Count
+		}
+	}
+	return counts
+}
+
+// WriteLCOV writes the profiles in the LCOV tracefile format.
+func WriteLCOV(w io.Writer, profiles []*Profile) error {
+	out := bufio.NewWriter(w)
+
+	for _, p := range profiles {
+		fmt.Fprintf(out, "TN:\nSF:%s\n", p.Path)
+
+		counts := p.lineCounts()
+		hit := 0
+		for line := 1; line <= strings.Count(p.Source, "\n")+1; line++ {
+			count, ok := counts[line]
+			if !ok {
+				continue
+			}
+			fmt.Fprintf(out, "DA:%d,%d\n", line, count)
+			if count > 0 {
+				hit++
+			}
+		}
+		fmt.Fprintf(out, "LF:%d\nLH:%d\n", len(counts), hit)
+
+		summary := p.Summary()
+		for block, b := range p.Branches {
+			for way, taken := range b.Taken {
+				// a branch whose line never ran wasn't evaluated at all
+				if counts[b.Line] == 0 && b.Taken[0]+b.Taken[1] == 0 {
+					fmt.Fprintf(out, "BRDA:%d,%d,%d,-\n", b.Line, block, way)
+				} else {
+					fmt.Fprintf(out, "BRDA:%d,%d,%d,%d\n", b.Line, block, way, taken)
+				}
+			}
+		}
+		fmt.Fprintf(out, "BRF:%d\nBRH:%d\nend_of_record\n", summary.Branches, summary.CoveredBranches)
+	}
+
+	return out.Flush()
+}
+
+const htmlHeader = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>golox coverage</title>
+<style>
+body { font-family: sans-serif; }
+pre { line-height: 1.4; }
+.line { display: block; }
+.count, .number { display: inline-block; width: 5em; text-align: right; color: #777; margin-right: 1em; }
+.covered { background: #dfd; }
+.partial { background: #ffc; }
+.uncovered { background: #fcc; }
+</style>
+</head>
+<body>
+`
+
+// WriteHTML writes a report showing the source of the profiles, each line
+// colored by whether it ran and whether all its branches did.
+func WriteHTML(w io.Writer, profiles []*Profile) error {
+	out := bufio.NewWriter(w)
+
+	var total Summary
+	for _, p := range profiles {
+		total = total.Add(p.Summary())
+	}
+
+	out.WriteString(htmlHeader)
+	fmt.Fprintf(out, "<h1>Coverage</h1>\n<p>%s</p>\n", total)
+
+	for _, p := range profiles {
+		fmt.Fprintf(out, "<h2>%s</h2>\n<p>%s</p>\n<pre>\n", html.EscapeString(p.Path), p.Summary())
+
+		counts := p.lineCounts()
+		partial := map[int]bool{}
+		for _, b := range p.Branches {
+			if b.Taken[0] == 0 || b.Taken[1] == 0 {
+				partial[b.Line] = true
+			}
+		}
+
+		for i, text := range strings.Split(p.Source, "\n") {
+			line := i + 1
+			class, count := "line", ""
+			if n, ok := counts[line]; ok {
+				count = fmt.Sprint(n)
+				switch {
+				case n == 0:
+					class += " uncovered"
+				case partial[line]:
+					class += " partial"
+				default:
+					class += " covered"
+				}
+			}
+
+			fmt.Fprintf(out, `<span class="%s"><span class="count">%s</span><span class="number">%d</span>%s</span>`+"\n",
+				class, count, line, html.EscapeString(text))
+		}
+		out.WriteString("</pre>\n")
+	}
+
+	out.WriteString("</body>\n</html>\n")
+	return out.Flush()
+}
@@ -0,0 +1,278 @@
+package dap
+
+import (
+	goerrors "errors"
+	"fmt"
+	"golox/errors"
+	"golox/expr"
+	"golox/interpreter"
+	"golox/parser"
+	"golox/scanner"
+	"golox/stepper"
+	"golox/stmt"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+type breakpoint struct {
+	line      int
+	condition expr.Expression[any]
+}
+
+// frame is an entry of the stack trace, the innermost first.
+type frame struct {
+	name string
+	line int
+	env  *interpreter.Environment
+}
+
+// errTerminated unwinds the program when the client ends the session.
+var (
+	errTerminated = goerrors.New("terminated")
+	errNotStopped = goerrors.New("the program isn't stopped")
+)
+
+// debugger is the interpreter hook, it decides where the program stops and
+// blocks it there until the client resumes it.
+type debugger struct {
+	// stopped reports where the program stopped, from the interpreter goroutine
+	stopped func(reason, description string)
+
+	mu          sync.Mutex
+	breakpoints map[int]*breakpoint
+	entry       bool
+	stepper     *stepper.Stepper
+
+	pause atomic.Bool
+	// closed when the session ends, a stopped program unwinds instead of resuming
+	quit     chan struct{}
+	quitOnce sync.Once
+
+	// the state of a stopped program, read by the server while the
+	// interpreter waits on resume
+	isStopped bool
+	interp    *interpreter.Interpreter
+	line      int
+	resume    chan stepper.Mode
+}
+
+func newDebugger(stopped func(reason, description string)) *debugger {
+	return &debugger{
+		stopped:     stopped,
+		breakpoints: map[int]*breakpoint{},
+		stepper:     stepper.New(),
+		resume:      make(chan stepper.Mode),
+		quit:        make(chan struct{}),
+	}
+}
+
+func (d *debugger) BeforeExecute(i *interpreter.Interpreter, st stmt.Statement[any]) {
+	select {
+	case <-d.quit:
+		panic(errTerminated)
+	default:
+	}
+
+	reason, description, line := d.check(i, st)
+	if reason == "" {
+		return
+	}
+
+	d.mu.Lock()
+	d.isStopped, d.interp, d.line = true, i, line
+	d.stepper.Stop(st, line)
+	d.mu.Unlock()
+
+	d.stopped(reason, description)
+
+	var mode stepper.Mode
+	select {
+	case mode = <-d.resume:
+	case <-d.quit:
+		panic(errTerminated)
+	}
+
+	d.mu.Lock()
+	d.isStopped = false
+	d.stepper.Resume(mode, len(i.Calls()))
+	d.mu.Unlock()
+}
+
+// abort ends the program at its next statement, or right away when it is stopped.
+func (d *debugger) abort() {
+	d.quitOnce.Do(func() { close(d.quit) })
+}
+
+// check returns the reason to stop before st, if any, and the line st starts on.
+func (d *debugger) check(i *interpreter.Interpreter, st stmt.Statement[any]) (reason, description string, line int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	line, ok := d.stepper.Visit(st)
+	if _, isBlock := st.(*stmt.BlockStmt[any]); isBlock {
+		return "", "", line
+	}
+	if d.pause.Swap(false) {
+		return "pause", "", line
+	}
+	if !ok {
+		return "", "", line
+	}
+
+	if d.entry {
+		d.entry = false
+		return "entry", "", line
+	}
+	if d.stepper.Stepping(len(i.Calls())) {
+		return "step", "", line
+	}
+
+	bp, ok := d.breakpoints[line]
+	if !ok {
+		return "", "", line
+	}
+	if bp.condition == nil {
+		return "breakpoint", "", line
+	}
+
+	value, err := i.Evaluate(bp.condition, i.Environment())
+	if err != nil {
+		// a broken condition stops the program so that it can be fixed
+		return "breakpoint", fmt.Sprintf("Breakpoint condition failed: %v", err), line
+	}
+	if value == nil || value == false {
+		return "", "", line
+	}
+	return "breakpoint", "", line
+}
+
+// setBreakpoints replaces the breakpoints, lines lists where statements start
+// and is nil when the program isn't known yet.
+func (d *debugger) setBreakpoints(requested []SourceBreakpoint, lines map[int]bool) []Breakpoint {
+	breakpoints := map[int]*breakpoint{}
+	result := []Breakpoint{}
+
+	for _, sb := range requested {
+		bp := &breakpoint{line: sb.Line}
+		verified := Breakpoint{Verified: true, Line: sb.Line}
+
+		if sb.Condition != "" {
+			condition, err := parseExpression(sb.Condition)
+			if err != nil {
+				verified.Verified, verified.Message = false, err.Error()
+			}
+			bp.condition = condition
+		}
+		if lines != nil && !lines[sb.Line] {
+			verified.Verified, verified.Message = false, "No statement starts on this line."
+		}
+
+		if verified.Verified {
+			breakpoints[sb.Line] = bp
+		}
+		result = append(result, verified)
+	}
+
+	d.mu.Lock()
+	d.breakpoints = breakpoints
+	d.mu.Unlock()
+	return result
+}
+
+// resumeWith restarts a stopped program, it fails when the program isn't stopped.
+func (d *debugger) resumeWith(mode stepper.Mode) error {
+	d.mu.Lock()
+	stopped := d.isStopped
+	d.mu.Unlock()
+
+	if !stopped {
+		return errNotStopped
+	}
+	d.resume <- mode
+	return nil
+}
+
+// frames returns the stack of a stopped program.
+func (d *debugger) frames() ([]frame, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.isStopped {
+		return nil, errNotStopped
+	}
+
+	calls := d.interp.Calls()
+	name := func(depth int) string {
+		if depth == 0 {
+			return "<script>"
+		}
+		return calls[depth-1].Name
+	}
+
+	frames := []frame{{name: name(len(calls)), line: d.line, env: d.interp.Environment()}}
+	for depth := len(calls) - 1; depth >= 0; depth-- {
+		frames = append(frames, frame{name: name(depth), line: calls[depth].Paren.Line, env: calls[depth].Environment})
+	}
+	return frames, nil
+}
+
+// evaluate evaluates an expression typed by the user in env.
+func (d *debugger) evaluate(source string, env *interpreter.Environment) (string, error) {
+	e, err := parseExpression(source)
+	if err != nil {
+		return "", err
+	}
+
+	value, err := d.interp.Evaluate(e, env)
+	if err != nil {
+		return "", err
+	}
+	return d.interp.Stringify(value), nil
+}
+
+func parseExpression(source string) (expr.Expression[any], error) {
+	s := scanner.NewScanner(source)
+	s.Reporter = errors.NewErrorReporter(io.Discard)
+	tokens := s.ScanTokens()
+	if s.HadError() {
+		return nil, fmt.Errorf("%s", s.Errors()[0].Message)
+	}
+	return parser.NewParser(tokens).ParseExpression()
+}
+
+// statementLines returns the lines where a statement starts, the places a breakpoint can stop.
+func statementLines(statements []stmt.Statement[any]) map[int]bool {
+	lines := map[int]bool{}
+
+	var walk func(st stmt.Statement[any])
+	walk = func(st stmt.Statement[any]) {
+		switch s := st.(type) {
+		case nil:
+			return
+		case *stmt.BlockStmt[any]:
+			for _, child := range s.Stmts {
+				walk(child)
+			}
+			return
+		case *stmt.IfStmt[any]:
+			walk(s.ThenBranch)
+			walk(s.ElseBranch)
+		case *stmt.WhileStmt[any]:
+			walk(s.Body)
+		case *stmt.TestStmt[any]:
+			walk(s.Body)
+		case *stmt.BenchStmt[any]:
+			walk(s.Body)
+		}
+
+		if line, _ := stmt.Lines(st); line > 0 {
+			lines[line] = true
+		}
+	}
+
+	for _, st := range statements {
+		walk(st)
+	}
+	return lines
+}
@@ -0,0 +1,420 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	goerrors "errors"
+	"fmt"
+	"golox/errors"
+	"golox/interpreter"
+	"golox/number"
+	"golox/parser"
+	"golox/scanner"
+	"golox/stepper"
+	"golox/stmt"
+	"golox/wire"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Server is a debug adapter speaking DAP over a pair of streams, it debugs one program per session.
+type Server struct {
+	in *bufio.Reader
+
+	// guards the writes, events come from the interpreter goroutine too
+	mu  sync.Mutex
+	out io.Writer
+	seq int
+	err error
+
+	debugger   *debugger
+	program    string
+	statements []stmt.Statement[any]
+	lines      map[int]bool
+	noDebug    bool
+	launched   bool
+	configured bool
+	// closed once the program finished, nil until it starts
+	done chan struct{}
+
+	// variable references handed out while the program is stopped
+	scopes map[int]*interpreter.Environment
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	s := &Server{in: bufio.NewReader(in), out: out}
+	s.debugger = newDebugger(func(reason, description string) {
+		s.event("stopped", StoppedEvent{Reason: reason, Description: description, ThreadID: threadID, AllThreadsStopped: true})
+	})
+	return s
+}
+
+// Run serves requests until the client disconnects or closes the input.
+func (s *Server) Run() error {
+	defer s.stop()
+
+	for {
+		body, err := wire.Read(s.in)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			return fmt.Errorf("invalid message: %v", err)
+		}
+
+		result, err := s.dispatch(req)
+		s.respond(req, result, err)
+		if req.Command == "initialize" && err == nil {
+			s.event("initialized", nil)
+		}
+
+		if req.Command == "disconnect" {
+			return s.writeErr()
+		}
+		if err := s.writeErr(); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) dispatch(req request) (any, error) {
+	switch req.Command {
+	case "initialize":
+		return map[string]any{
+			"supportsConfigurationDoneRequest": true,
+			"supportsConditionalBreakpoints":   true,
+			"supportsEvaluateForHovers":        true,
+			"supportsTerminateRequest":         true,
+		}, nil
+
+	case "launch":
+		var args LaunchArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return nil, s.launch(args)
+
+	case "setBreakpoints":
+		var args SetBreakpointsArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return map[string]any{"breakpoints": s.setBreakpoints(args)}, nil
+
+	case "configurationDone":
+		s.configured = true
+		s.start()
+		return nil, nil
+
+	case "threads":
+		return map[string]any{"threads": []Thread{{ID: threadID, Name: "main"}}}, nil
+
+	case "stackTrace":
+		return s.stackTrace()
+
+	case "scopes":
+		var args ScopesArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.scopesOf(args.FrameID)
+
+	case "variables":
+		var args VariablesArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.variables(args.VariablesReference)
+
+	case "evaluate":
+		var args EvaluateArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.evaluate(args)
+
+	case "continue":
+		return map[string]any{"allThreadsContinued": true}, s.resume(stepper.Continue)
+	case "next":
+		return nil, s.resume(stepper.StepOver)
+	case "stepIn":
+		return nil, s.resume(stepper.StepIn)
+	case "stepOut":
+		return nil, s.resume(stepper.StepOut)
+
+	case "pause":
+		s.debugger.pause.Store(true)
+		return nil, nil
+
+	case "disconnect", "terminate":
+		s.stop()
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("unsupported request: %s", req.Command)
+}
+
+func (s *Server) launch(args LaunchArguments) error {
+	source, err := os.ReadFile(args.Program)
+	if err != nil {
+		return err
+	}
+
+	// scan errors go back in the response, stdout belongs to the protocol
+	sc := scanner.NewScanner(string(source))
+	sc.Reporter = errors.NewErrorReporter(io.Discard)
+	tokens := sc.ScanTokens()
+	var problems []string
+	for _, d := range sc.Errors() {
+		problems = append(problems, d.String())
+	}
+
+	statements, errs := parser.NewParser(tokens).Parse()
+	for _, err := range errs {
+		problems = append(problems, err.Error())
+	}
+	if len(problems) > 0 {
+		return goerrors.New(strings.Join(problems, "\n"))
+	}
+
+	s.program, _ = filepath.Abs(args.Program)
+	s.statements = statements
+	s.lines = statementLines(statements)
+	s.noDebug = args.NoDebug
+	s.debugger.entry = args.StopOnEntry
+	s.launched = true
+
+	s.start()
+	return nil
+}
+
+func (s *Server) setBreakpoints(args SetBreakpointsArguments) []Breakpoint {
+	path, _ := filepath.Abs(args.Source.Path)
+	if s.launched && path != s.program {
+		result := []Breakpoint{}
+		for _, sb := range args.Breakpoints {
+			result = append(result, Breakpoint{Line: sb.Line, Message: "Not the program being debugged."})
+		}
+		return result
+	}
+	return s.debugger.setBreakpoints(args.Breakpoints, s.lines)
+}
+
+// start runs the program once it is launched and the client is done configuring the breakpoints.
+func (s *Server) start() {
+	if !s.launched || !s.configured || s.done != nil {
+		return
+	}
+
+	interp := interpreter.NewInterpreter()
+	interp.Out = &output{server: s, category: "stdout"}
+	if !s.noDebug {
+		interp.Hook = s.debugger
+	}
+
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+
+		exitCode := 0
+		func() {
+			defer func() {
+				if r := recover(); r != nil && r != errTerminated {
+					panic(r)
+				}
+			}()
+
+			errors.HadRuntimeError = false
+			interp.Interpret(s.statements)
+			if errors.HadRuntimeError {
+				exitCode = 70
+			}
+		}()
+
+		s.event("exited", map[string]any{"exitCode": exitCode})
+		s.event("terminated", nil)
+	}()
+}
+
+// stop ends the program, if it runs, and waits for it.
+func (s *Server) stop() {
+	if s.done == nil {
+		return
+	}
+
+	s.debugger.abort()
+	<-s.done
+}
+
+func (s *Server) resume(mode stepper.Mode) error {
+	// the references of a stopped program are only valid until it resumes
+	s.scopes = nil
+	return s.debugger.resumeWith(mode)
+}
+
+func (s *Server) stackTrace() (any, error) {
+	frames, err := s.debugger.frames()
+	if err != nil {
+		return nil, err
+	}
+
+	source := Source{Name: filepath.Base(s.program), Path: s.program}
+	stack := []StackFrame{}
+	for i, f := range frames {
+		stack = append(stack, StackFrame{ID: i + 1, Name: f.name, Source: source, Line: f.line, Column: 1})
+	}
+	return map[string]any{"stackFrames": stack, "totalFrames": len(stack)}, nil
+}
+
+// frame returns the frame a frame id refers to.
+func (s *Server) frame(id int) (frame, error) {
+	frames, err := s.debugger.frames()
+	if err != nil {
+		return frame{}, err
+	}
+	if id < 1 || id > len(frames) {
+		return frame{}, fmt.Errorf("unknown frame %d", id)
+	}
+	return frames[id-1], nil
+}
+
+// scopesOf lists the environments visible from a frame, from the innermost one to the globals.
+func (s *Server) scopesOf(frameID int) (any, error) {
+	f, err := s.frame(frameID)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.scopes == nil {
+		s.scopes = map[int]*interpreter.Environment{}
+	}
+
+	scopes := []Scope{}
+	for env := f.env; env != nil; env = env.Parent() {
+		name := "Block"
+		switch {
+		case env.Parent() == nil:
+			name = "Globals"
+		case env.Parent().Parent() == nil:
+			name = "Script"
+		case env == f.env:
+			name = "Locals"
+		}
+
+		reference := len(s.scopes) + 1
+		s.scopes[reference] = env
+		scopes = append(scopes, Scope{Name: name, VariablesReference: reference})
+	}
+	return map[string]any{"scopes": scopes}, nil
+}
+
+func (s *Server) variables(reference int) (any, error) {
+	env, ok := s.scopes[reference]
+	if !ok {
+		return nil, fmt.Errorf("unknown variables reference %d", reference)
+	}
+
+	values := env.Values()
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	variables := []Variable{}
+	for _, name := range names {
+		value := values[name]
+		variables = append(variables, Variable{Name: name, Value: s.debugger.interp.Stringify(value), Type: typeName(value)})
+	}
+	return map[string]any{"variables": variables}, nil
+}
+
+func (s *Server) evaluate(args EvaluateArguments) (any, error) {
+	id := args.FrameID
+	if id == 0 {
+		id = 1
+	}
+
+	f, err := s.frame(id)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.debugger.evaluate(args.Expression, f.env)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"result": result, "variablesReference": 0}, nil
+}
+
+func typeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return "boolean"
+	case int64, *big.Int, float64:
+		return "number"
+	case number.Decimal:
+		return "decimal"
+	case string:
+		return "string"
+	case interpreter.LoxCallable:
+		return "function"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func (s *Server) respond(req request, body any, err error) {
+	r := response{Type: "response", RequestSeq: req.Seq, Success: err == nil, Command: req.Command, Body: body}
+	if err != nil {
+		r.Message, r.Body = err.Error(), nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	r.Seq = s.seq
+	s.write(r)
+}
+
+func (s *Server) event(name string, body any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	s.write(event{Seq: s.seq, Type: "event", Event: name, Body: body})
+}
+
+// write sends a message, s.mu must be held.
+func (s *Server) write(value any) {
+	if s.err == nil {
+		s.err = wire.Write(s.out, value)
+	}
+}
+
+func (s *Server) writeErr() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// output forwards what the program prints to the client as output events.
+type output struct {
+	server   *Server
+	category string
+}
+
+func (o *output) Write(p []byte) (int, error) {
+	o.server.event("output", OutputEvent{Category: o.category, Output: string(p)})
+	return len(p), nil
+}
@@ -0,0 +1,339 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"golox/wire"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// message is any message from the server, decoded loosely.
+type message struct {
+	Type       string          `json:"type"`
+	RequestSeq int             `json:"request_seq"`
+	Success    bool            `json:"success"`
+	Command    string          `json:"command"`
+	Message    string          `json:"message"`
+	Event      string          `json:"event"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// client drives a server in process, the way an editor would.
+type client struct {
+	t        *testing.T
+	in       io.WriteCloser
+	messages chan message
+	// events received while waiting for a response
+	events []message
+	// everything the program printed so far
+	printed strings.Builder
+	seq     int
+	done    chan error
+}
+
+func newClient(t *testing.T) *client {
+	t.Helper()
+
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	c := &client{t: t, in: clientOut, messages: make(chan message, 100), done: make(chan error, 1)}
+
+	go func() {
+		c.done <- NewServer(serverIn, serverOut).Run()
+		serverOut.Close()
+	}()
+	go func() {
+		defer close(c.messages)
+		r := bufio.NewReader(clientIn)
+		for {
+			body, err := wire.Read(r)
+			if err != nil {
+				return
+			}
+			var msg message
+			if err := json.Unmarshal(body, &msg); err != nil {
+				t.Errorf("decode %s: %v", body, err)
+				return
+			}
+			c.messages <- msg
+		}
+	}()
+
+	t.Cleanup(func() { clientOut.Close() })
+	return c
+}
+
+func (c *client) next() message {
+	c.t.Helper()
+	select {
+	case msg, ok := <-c.messages:
+		if !ok {
+			c.t.Fatal("the server closed the connection")
+		}
+		if msg.Event == "output" {
+			c.printed.WriteString(decode[OutputEvent](c.t, msg.Body).Output)
+		}
+		return msg
+	case <-time.After(5 * time.Second):
+		c.t.Fatal("timed out waiting for the server")
+	}
+	return message{}
+}
+
+// request sends a request and returns its response, failing the test when it isn't successful.
+func (c *client) request(command string, arguments any) message {
+	c.t.Helper()
+	msg := c.send(command, arguments)
+	if !msg.Success {
+		c.t.Fatalf("%s failed: %s", command, msg.Message)
+	}
+	return msg
+}
+
+func (c *client) send(command string, arguments any) message {
+	c.t.Helper()
+	c.seq++
+	if err := wire.Write(c.in, map[string]any{"seq": c.seq, "type": "request", "command": command, "arguments": arguments}); err != nil {
+		c.t.Fatalf("write: %v", err)
+	}
+
+	for {
+		msg := c.next()
+		if msg.Type == "response" && msg.RequestSeq == c.seq {
+			return msg
+		}
+		c.events = append(c.events, msg)
+	}
+}
+
+// output waits for the program to terminate and returns everything it printed.
+func (c *client) output() string {
+	c.t.Helper()
+	c.waitEvent("terminated")
+	return c.printed.String()
+}
+
+// waitEvent returns the next event with one of the given names, skipping the others.
+func (c *client) waitEvent(names ...string) message {
+	c.t.Helper()
+	for {
+		var msg message
+		if len(c.events) > 0 {
+			msg, c.events = c.events[0], c.events[1:]
+		} else {
+			msg = c.next()
+		}
+		if slices.Contains(names, msg.Event) {
+			return msg
+		}
+	}
+}
+
+func decode[T any](t *testing.T, data json.RawMessage) T {
+	t.Helper()
+	var value T
+	if err := json.Unmarshal(data, &value); err != nil {
+		t.Fatalf("decode %s: %v", data, err)
+	}
+	return value
+}
+
+// start launches a program, breakpoints maps lines to their conditions.
+func (c *client) start(source string, stopOnEntry bool, breakpoints map[int]string) []Breakpoint {
+	c.t.Helper()
+	path := filepath.Join(c.t.TempDir(), "program.lox")
+	if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+		c.t.Fatal(err)
+	}
+
+	c.request("initialize", map[string]any{"adapterID": "golox"})
+	c.waitEvent("initialized")
+	c.request("launch", map[string]any{"program": path, "stopOnEntry": stopOnEntry})
+
+	var requested []SourceBreakpoint
+	for line, condition := range breakpoints {
+		requested = append(requested, SourceBreakpoint{Line: line, Condition: condition})
+	}
+	slices.SortFunc(requested, func(a, b SourceBreakpoint) int { return a.Line - b.Line })
+	set := c.request("setBreakpoints", map[string]any{"source": map[string]any{"path": path}, "breakpoints": requested})
+
+	c.request("configurationDone", nil)
+	return decode[struct{ Breakpoints []Breakpoint }](c.t, set.Body).Breakpoints
+}
+
+// stopped waits for the program to stop and returns the reason and the line.
+func (c *client) stopped() (string, int) {
+	c.t.Helper()
+	reason := decode[StoppedEvent](c.t, c.waitEvent("stopped", "terminated").Body).Reason
+
+	trace := c.request("stackTrace", map[string]any{"threadId": threadID})
+	frames := decode[struct{ StackFrames []StackFrame }](c.t, trace.Body).StackFrames
+	return reason, frames[0].Line
+}
+
+func (c *client) evaluate(expression string) string {
+	c.t.Helper()
+	msg := c.request("evaluate", map[string]any{"expression": expression, "frameId": 1})
+	return decode[struct{ Result string }](c.t, msg.Body).Result
+}
+
+func (c *client) disconnect() {
+	c.t.Helper()
+	c.request("disconnect", nil)
+	if err := <-c.done; err != nil {
+		c.t.Errorf("server failed: %v", err)
+	}
+}
+
+func TestBreakpointsAndVariables(t *testing.T) {
+	c := newClient(t)
+	breakpoints := c.start("var a = 1;\n{\n  var b = a + 1;\n  print b;\n}\nprint a;\n", false, map[int]string{2: "", 4: ""})
+
+	if breakpoints[0].Verified || !breakpoints[1].Verified {
+		t.Errorf("got %+v, want only the breakpoint on line 4 verified", breakpoints)
+	}
+
+	if reason, line := c.stopped(); reason != "breakpoint" || line != 4 {
+		t.Fatalf("stopped for %q on line %d, want a breakpoint on line 4", reason, line)
+	}
+
+	scopes := decode[struct{ Scopes []Scope }](t, c.request("scopes", map[string]any{"frameId": 1}).Body).Scopes
+	var names []string
+	for _, scope := range scopes {
+		names = append(names, scope.Name)
+	}
+	if want := []string{"Locals", "Script", "Globals"}; !slices.Equal(names, want) {
+		t.Fatalf("got scopes %v, want %v", names, want)
+	}
+
+	variables := func(scope Scope) []Variable {
+		msg := c.request("variables", map[string]any{"variablesReference": scope.VariablesReference})
+		return decode[struct{ Variables []Variable }](t, msg.Body).Variables
+	}
+	if got := variables(scopes[0]); len(got) != 1 || got[0].Name != "b" || got[0].Value != "2" || got[0].Type != "number" {
+		t.Errorf("got locals %+v", got)
+	}
+	if got := variables(scopes[1]); len(got) != 1 || got[0].Name != "a" || got[0].Value != "1" {
+		t.Errorf("got script variables %+v", got)
+	}
+
+	if got := c.evaluate("a + b"); got != "3" {
+		t.Errorf("got a + b = %s, want 3", got)
+	}
+	if msg := c.send("evaluate", map[string]any{"expression": "missing", "frameId": 1}); msg.Success {
+		t.Error("evaluating an undefined variable succeeded")
+	}
+
+	c.request("continue", map[string]any{"threadId": threadID})
+	if got := c.output(); got != "2\n1\n" {
+		t.Errorf("got output %q", got)
+	}
+	c.disconnect()
+}
+
+func TestConditionalBreakpoint(t *testing.T) {
+	c := newClient(t)
+	breakpoints := c.start("for (var i = 0; i < 5; i = i + 1) {\n  print i;\n}\n", false, map[int]string{2: "i == 3"})
+	if !breakpoints[0].Verified {
+		t.Fatalf("got %+v", breakpoints)
+	}
+
+	if reason, line := c.stopped(); reason != "breakpoint" || line != 2 {
+		t.Fatalf("stopped for %q on line %d", reason, line)
+	}
+	if got := c.evaluate("i"); got != "3" {
+		t.Errorf("stopped with i = %s, want 3", got)
+	}
+
+	c.request("continue", map[string]any{"threadId": threadID})
+	if got := c.output(); got != "0\n1\n2\n3\n4\n" {
+		t.Errorf("got output %q", got)
+	}
+	c.disconnect()
+}
+
+func TestInvalidCondition(t *testing.T) {
+	c := newClient(t)
+	breakpoints := c.start("print 1;\n", false, map[int]string{1: "1 +"})
+	if breakpoints[0].Verified || breakpoints[0].Message == "" {
+		t.Errorf("got %+v, want an unverified breakpoint", breakpoints)
+	}
+	c.output()
+	c.disconnect()
+}
+
+func TestStepping(t *testing.T) {
+	c := newClient(t)
+	c.start("var a = 1;\nif (a > 0) {\n  a = a + 1;\n}\nprint clock() > 0;\nprint a;\n", true, nil)
+
+	if reason, line := c.stopped(); reason != "entry" || line != 1 {
+		t.Fatalf("stopped for %q on line %d, want the entry", reason, line)
+	}
+
+	steps := []struct {
+		command string
+		line    int
+	}{
+		{"next", 2},
+		{"stepIn", 3},
+		{"next", 5},
+		{"stepIn", 6},
+	}
+	for _, step := range steps {
+		c.request(step.command, map[string]any{"threadId": threadID})
+		if reason, line := c.stopped(); reason != "step" || line != step.line {
+			t.Fatalf("%s stopped for %q on line %d, want line %d", step.command, reason, line, step.line)
+		}
+	}
+
+	// there is no caller to step out to
+	c.request("stepOut", map[string]any{"threadId": threadID})
+	if got := c.output(); got != "true\n2\n" {
+		t.Errorf("got output %q", got)
+	}
+	c.disconnect()
+}
+
+func TestPauseAndDisconnect(t *testing.T) {
+	c := newClient(t)
+	c.start("var a = 0;\nwhile (true) {\n  a = a + 1;\n}\n", false, nil)
+
+	if msg := c.send("next", map[string]any{"threadId": threadID}); msg.Success {
+		t.Error("stepping a running program succeeded")
+	}
+
+	c.request("pause", map[string]any{"threadId": threadID})
+	if reason, line := c.stopped(); reason != "pause" || line != 3 {
+		t.Fatalf("stopped for %q on line %d, want a pause on line 3", reason, line)
+	}
+
+	// the disconnect ends the endless loop
+	c.disconnect()
+}
+
+func TestBreakpointInLoop(t *testing.T) {
+	c := newClient(t)
+	c.start("var i = 0;\nwhile (i < 3) {\n  i = i + 1;\n}\nprint i;\n", false, map[int]string{3: ""})
+
+	for want := 0; want < 3; want++ {
+		if reason, line := c.stopped(); reason != "breakpoint" || line != 3 {
+			t.Fatalf("stop %d for %q on line %d, want a breakpoint on line 3", want, reason, line)
+		}
+		if got := c.evaluate("i"); got != strconv.Itoa(want) {
+			t.Errorf("stopped with i = %s, want %d", got, want)
+		}
+		c.request("continue", map[string]any{"threadId": threadID})
+	}
+
+	if got := c.output(); got != "3\n" {
+		t.Errorf("got output %q", got)
+	}
+	c.disconnect()
+}
@@ -0,0 +1,376 @@
+// Package debugger is a command line debugger in the style of gdb.
+package debugger
+
+import (
+	"bufio"
+	goerrors "errors"
+	"fmt"
+	"golox/errors"
+	"golox/expr"
+	"golox/interpreter"
+	"golox/parser"
+	"golox/scanner"
+	"golox/stepper"
+	"golox/stmt"
+	"golox/token"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const help = `Commands:
+  break <line> [if <expr>]  stop before the statement on line, when expr is true
+  watch <name>              stop when an assignment changes the variable
+  delete <n>                remove breakpoint or watchpoint n
+  run                       start the program
+  step                      run to the next statement
+  next                      run to the next statement, stepping over calls
+  continue                  run to the next breakpoint
+  print <expr>              evaluate an expression in the current scope
+  locals                    list the variables in scope
+  backtrace                 list the calls in progress
+  quit                      leave the debugger
+An empty line repeats the previous command.`
+
+// point is a breakpoint when line is set, a watchpoint otherwise.
+type point struct {
+	number    int
+	line      int
+	condition expr.Expression[any]
+	watch     string
+}
+
+// errQuit unwinds the program when the user quits while it runs.
+var errQuit = goerrors.New("quit")
+
+// Debugger runs a program under the control of commands read from its input.
+type Debugger struct {
+	lines      []string
+	statements []stmt.Statement[any]
+	in         *bufio.Scanner
+	out        io.Writer
+
+	points []*point
+	// the number of the next breakpoint or watchpoint
+	next int
+
+	// the running program, nil between runs
+	interp      *interpreter.Interpreter
+	stepper     *stepper.Stepper
+	lastCommand string
+}
+
+func New(source string, statements []stmt.Statement[any], in io.Reader, out io.Writer) *Debugger {
+	return &Debugger{
+		lines:      strings.Split(source, "\n"),
+		statements: statements,
+		in:         bufio.NewScanner(in),
+		out:        out,
+		next:       1,
+		stepper:    stepper.New(),
+	}
+}
+
+// Run reads commands until the user quits or the input ends.
+func (d *Debugger) Run() {
+	fmt.Fprintln(d.out, `Type "help" for the commands.`)
+
+	for {
+		line, ok := d.readCommand()
+		if !ok {
+			return
+		}
+
+		name, arg := splitCommand(line)
+		switch name {
+		case "run", "r":
+			if d.start() {
+				return
+			}
+		case "quit", "q":
+			return
+		case "step", "s", "next", "n", "continue", "c", "print", "p", "locals", "backtrace", "bt":
+			fmt.Fprintln(d.out, "The program is not being run.")
+		default:
+			d.setup(name, arg)
+		}
+	}
+}
+
+// start runs the program, it reports whether the user quit while it ran.
+func (d *Debugger) start() (quit bool) {
+	d.interp = interpreter.NewInterpreter()
+	d.interp.Out = d.out
+	d.interp.Hook = d
+	d.stepper.Reset()
+
+	defer func() {
+		d.interp = nil
+		if r := recover(); r != nil {
+			if r != errQuit {
+				panic(r)
+			}
+			quit = true
+		}
+	}()
+
+	errors.HadRuntimeError = false
+	d.interp.Interpret(d.statements)
+
+	if errors.HadRuntimeError {
+		fmt.Fprintln(d.out, "Program exited with a runtime error.")
+	} else {
+		fmt.Fprintln(d.out, "Program exited normally.")
+	}
+	return false
+}
+
+func (d *Debugger) BeforeExecute(i *interpreter.Interpreter, st stmt.Statement[any]) {
+	line, ok := d.stepper.Visit(st)
+	if !ok {
+		return
+	}
+
+	if d.stepper.Stepping(len(i.Calls())) {
+		d.stop(st, line, "")
+		return
+	}
+
+	for _, p := range d.points {
+		if p.line != line {
+			continue
+		}
+
+		if p.condition != nil {
+			value, err := i.Evaluate(p.condition, i.Environment())
+			if err != nil {
+				fmt.Fprintf(d.out, "Error in the condition of breakpoint %d: %v\n", p.number, err)
+			} else if value == nil || value == false {
+				continue
+			}
+		}
+
+		d.stop(st, line, fmt.Sprintf("Breakpoint %d, line %d", p.number, line))
+		return
+	}
+}
+
+func (d *Debugger) AfterAssign(i *interpreter.Interpreter, name token.Token, old, value any) {
+	if i.IsEqual(old, value) {
+		return
+	}
+
+	for _, p := range d.points {
+		if p.watch == name.Lexeme {
+			d.stop(nil, name.Line, fmt.Sprintf("Watchpoint %d: %s\n\nOld value = %s\nNew value = %s",
+				p.number, p.watch, i.Stringify(old), i.Stringify(value)))
+			return
+		}
+	}
+}
+
+// stop shows where the program stopped and runs commands until one resumes it,
+// st is the statement it stopped before, nil in the middle of one.
+func (d *Debugger) stop(st stmt.Statement[any], line int, message string) {
+	if message != "" {
+		fmt.Fprintln(d.out, message)
+	}
+	d.showLine(line)
+	d.stepper.Stop(st, line)
+
+	for {
+		command, ok := d.readCommand()
+		if !ok {
+			panic(errQuit)
+		}
+
+		name, arg := splitCommand(command)
+		switch name {
+		case "step", "s":
+			d.resume(stepper.StepIn)
+			return
+		case "next", "n":
+			d.resume(stepper.StepOver)
+			return
+		case "continue", "c":
+			d.resume(stepper.Continue)
+			return
+		case "run", "r":
+			fmt.Fprintln(d.out, "The program is already running.")
+		case "quit", "q":
+			panic(errQuit)
+		case "print", "p":
+			d.print(arg)
+		case "locals":
+			d.locals()
+		case "backtrace", "bt":
+			d.backtrace(line)
+		default:
+			d.setup(name, arg)
+		}
+	}
+}
+
+func (d *Debugger) resume(mode stepper.Mode) {
+	d.stepper.Resume(mode, len(d.interp.Calls()))
+}
+
+// setup runs the commands available whether the program runs or not.
+func (d *Debugger) setup(name, arg string) {
+	switch name {
+	case "break", "b":
+		d.addBreakpoint(arg)
+	case "watch", "w":
+		if !isIdentifier(arg) {
+			fmt.Fprintln(d.out, "Usage: watch <name>")
+			return
+		}
+		d.add(&point{watch: arg})
+		fmt.Fprintf(d.out, "Watchpoint %d: %s\n", d.next-1, arg)
+	case "delete", "d":
+		d.delete(arg)
+	case "help", "h":
+		fmt.Fprintln(d.out, help)
+	case "":
+	default:
+		fmt.Fprintf(d.out, "Unknown command %q, try \"help\".\n", name)
+	}
+}
+
+func (d *Debugger) addBreakpoint(arg string) {
+	lineText, conditionText, hasCondition := strings.Cut(arg, " if ")
+	line, err := strconv.Atoi(strings.TrimSpace(lineText))
+	if err != nil || line < 1 || line > len(d.lines) {
+		fmt.Fprintln(d.out, "Usage: break <line> [if <expr>]")
+		return
+	}
+
+	p := &point{line: line}
+	if hasCondition {
+		if p.condition, err = parseExpression(conditionText); err != nil {
+			fmt.Fprintf(d.out, "Invalid condition: %v\n", err)
+			return
+		}
+	}
+
+	d.add(p)
+	fmt.Fprintf(d.out, "Breakpoint %d at line %d\n", p.number, line)
+}
+
+func (d *Debugger) add(p *point) {
+	p.number = d.next
+	d.next++
+	d.points = append(d.points, p)
+}
+
+func (d *Debugger) delete(arg string) {
+	number, err := strconv.Atoi(arg)
+	index := slices.IndexFunc(d.points, func(p *point) bool { return p.number == number })
+	if err != nil || index < 0 {
+		fmt.Fprintf(d.out, "No breakpoint or watchpoint number %s.\n", arg)
+		return
+	}
+	d.points = slices.Delete(d.points, index, index+1)
+}
+
+func (d *Debugger) print(source string) {
+	e, err := parseExpression(source)
+	if err != nil {
+		fmt.Fprintln(d.out, err)
+		return
+	}
+
+	value, err := d.interp.Evaluate(e, d.interp.Environment())
+	if err != nil {
+		fmt.Fprintln(d.out, err)
+		return
+	}
+	fmt.Fprintln(d.out, d.interp.Stringify(value))
+}
+
+// locals lists the variables visible from the current scope, the globals aside.
+func (d *Debugger) locals() {
+	seen := map[string]bool{}
+	found := false
+
+	for env := d.interp.Environment(); env != nil && env != d.interp.Globals(); env = env.Parent() {
+		values := env.Values()
+		names := make([]string, 0, len(values))
+		for name := range values {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+
+		// an inner declaration hides the outer ones
+		for _, name := range names {
+			if !seen[name] {
+				seen[name], found = true, true
+				fmt.Fprintf(d.out, "%s = %s\n", name, d.interp.Stringify(values[name]))
+			}
+		}
+	}
+
+	if !found {
+		fmt.Fprintln(d.out, "No locals.")
+	}
+}
+
+func (d *Debugger) backtrace(line int) {
+	calls := d.interp.Calls()
+	name := func(depth int) string {
+		if depth == 0 {
+			return "<script>"
+		}
+		return calls[depth-1].Name
+	}
+
+	fmt.Fprintf(d.out, "#0  %s at line %d\n", name(len(calls)), line)
+	for depth := len(calls) - 1; depth >= 0; depth-- {
+		fmt.Fprintf(d.out, "#%d  %s at line %d\n", len(calls)-depth, name(depth), calls[depth].Paren.Line)
+	}
+}
+
+func (d *Debugger) showLine(line int) {
+	if line >= 1 && line <= len(d.lines) {
+		fmt.Fprintf(d.out, "%d\t%s\n", line, d.lines[line-1])
+	}
+}
+
+// readCommand prompts for a command, an empty line repeats the previous one.
+func (d *Debugger) readCommand() (string, bool) {
+	fmt.Fprint(d.out, "(golox) ")
+	if !d.in.Scan() {
+		fmt.Fprintln(d.out)
+		return "", false
+	}
+
+	command := strings.TrimSpace(d.in.Text())
+	if command == "" {
+		return d.lastCommand, true
+	}
+	d.lastCommand = command
+	return command, true
+}
+
+func splitCommand(line string) (name, arg string) {
+	name, arg, _ = strings.Cut(line, " ")
+	return name, strings.TrimSpace(arg)
+}
+
+func parseExpression(source string) (expr.Expression[any], error) {
+	s := scanner.NewScanner(source)
+	s.Reporter = errors.NewErrorReporter(io.Discard)
+	tokens := s.ScanTokens()
+	if s.HadError() {
+		return nil, goerrors.New(s.Errors()[0].Message)
+	}
+	return parser.NewParser(tokens).ParseExpression()
+}
+
+func isIdentifier(name string) bool {
+	s := scanner.NewScanner(name)
+	s.Reporter = errors.NewErrorReporter(io.Discard)
+	tokens := s.ScanTokens()
+	return !s.HadError() && len(tokens) == 2 && tokens[0].TokenType == token.IDENTIFIER
+}
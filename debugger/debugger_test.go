@@ -0,0 +1,195 @@
+package debugger
+
+import (
+	"golox/errors"
+	"golox/parser"
+	"golox/scanner"
+	"strings"
+	"testing"
+)
+
+// session runs the debugger on source with the given commands and returns its output.
+func session(t *testing.T, source string, commands ...string) string {
+	t.Helper()
+
+	statements, errs := parser.NewParser(scanner.NewScanner(source).ScanTokens()).Parse()
+	if errs != nil {
+		t.Fatalf("parse errors: %v", errs)
+	}
+
+	var out strings.Builder
+	New(source, statements, strings.NewReader(strings.Join(commands, "\n")+"\n"), &out).Run()
+	return out.String()
+}
+
+func expectOutput(t *testing.T, output string, want ...string) {
+	t.Helper()
+
+	// the pieces must appear in order
+	rest := output
+	for _, piece := range want {
+		index := strings.Index(rest, piece)
+		if index < 0 {
+			t.Fatalf("missing %q in:\n%s", piece, output)
+		}
+		rest = rest[index+len(piece):]
+	}
+}
+
+const program = `var a = 1;
+{
+  var b = a + 1;
+  print b;
+}
+for (var i = 0; i < 3; i = i + 1) {
+  a = a + i;
+}
+print a;
+`
+
+func TestBreakpoints(t *testing.T) {
+	output := session(t, program,
+		"break 4",
+		"break 7 if i == 2",
+		"run",
+		"print a + b",
+		"locals",
+		"backtrace",
+		"continue",
+		"print i",
+		"continue",
+	)
+
+	expectOutput(t, output,
+		"Breakpoint 1 at line 4",
+		"Breakpoint 2 at line 7",
+		"Breakpoint 1, line 4\n4\t  print b;",
+		"(golox) 3\n",
+		"(golox) b = 2\na = 1\n",
+		"#0  <script> at line 4",
+		"2\nBreakpoint 2, line 7\n7\t  a = a + i;",
+		"(golox) 2\n",
+		"4\nProgram exited normally.",
+	)
+}
+
+func TestStepping(t *testing.T) {
+	output := session(t, program,
+		"break 1",
+		"run",
+		"next",
+		"step",
+		"",
+		"quit",
+	)
+
+	expectOutput(t, output,
+		"Breakpoint 1, line 1",
+		"3\t  var b = a + 1;",
+		"4\t  print b;",
+		"2\n6\tfor",
+	)
+	if strings.Contains(output, "Program exited") {
+		t.Error("the program ran to the end after quit")
+	}
+}
+
+func TestWatchpoints(t *testing.T) {
+	output := session(t, program,
+		"watch a",
+		"run",
+		"continue",
+		"delete 1",
+		"continue",
+	)
+
+	// the first iteration adds 0 and doesn't change a
+	expectOutput(t, output,
+		"Watchpoint 1: a\n",
+		"Watchpoint 1: a\n\nOld value = 1\nNew value = 2\n7\t  a = a + i;",
+		"Old value = 2\nNew value = 4\n",
+		"4\nProgram exited normally.",
+	)
+}
+
+func TestWatchpointIgnoresEqualValues(t *testing.T) {
+	// the big integers and the decimals are new values after every operation
+	output := session(t, "var a = 10000000000000000000;\nvar d = 1.5d;\na = a + 0;\nd = d * 1;\na = a + 1;\n",
+		"watch a",
+		"watch d",
+		"run",
+		"continue",
+	)
+
+	expectOutput(t, output,
+		"Watchpoint 1: a\n\nOld value = 10000000000000000000\nNew value = 10000000000000000001\n",
+		"Program exited normally.",
+	)
+	if strings.Count(output, "Old value") != 1 {
+		t.Errorf("stopped on an assignment that kept the value:\n%s", output)
+	}
+}
+
+func TestCommandsBeforeRun(t *testing.T) {
+	output := session(t, program, "print a", "break 99", "bogus", "quit")
+
+	expectOutput(t, output,
+		"The program is not being run.",
+		"Usage: break <line> [if <expr>]",
+		`Unknown command "bogus"`,
+	)
+}
+
+func TestBreakpointInLoop(t *testing.T) {
+	output := session(t, "var i = 0;\nwhile (i < 3) {\n  i = i + 1;\n}\nprint i;\n",
+		"break 3",
+		"run",
+		"print i",
+		"continue",
+		"print i",
+		"continue",
+		"print i",
+		"continue",
+	)
+
+	expectOutput(t, output,
+		"Breakpoint 1, line 3\n3\t  i = i + 1;\n(golox) 0\n",
+		"Breakpoint 1, line 3\n3\t  i = i + 1;\n(golox) 1\n",
+		"Breakpoint 1, line 3\n3\t  i = i + 1;\n(golox) 2\n",
+		"3\nProgram exited normally.",
+	)
+	if got := strings.Count(output, "Breakpoint 1, line 3"); got != 3 {
+		t.Errorf("stopped %d times, want once per iteration:\n%s", got, output)
+	}
+}
+
+func TestStepOneLineLoop(t *testing.T) {
+	output := session(t, "for (var i = 0; i < 2; i = i + 1) print i;\nprint \"done\";\n",
+		"break 1",
+		"run",
+		"step",
+		"step",
+		"step",
+	)
+
+	// the loop header doesn't stop again, the body stops once per iteration
+	expectOutput(t, output,
+		"Breakpoint 1, line 1\n",
+		"(golox) 0\n1\tfor",
+		"(golox) 1\n2\tprint \"done\";",
+		"(golox) done\nProgram exited normally.",
+	)
+}
+
+func TestInvalidInput(t *testing.T) {
+	errors.HadError = false
+	output := session(t, program, `break 3 if "x`, "watch 1abc", "quit")
+
+	expectOutput(t, output, "Invalid condition: Unterminated string", "Usage: watch <name>")
+	if strings.Count(output, "Unterminated string") != 1 {
+		t.Errorf("the scan error is not shown once in:\n%s", output)
+	}
+	if errors.HadError {
+		t.Error("the scan errors of commands set HadError")
+	}
+}
@@ -0,0 +1,48 @@
+package errors
+
+import "fmt"
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	if s == SeverityWarning {
+		return "Warning"
+	}
+	return "Error"
+}
+
+// Span is the source range a diagnostic refers to, columns are 0 when only the lines are known.
+type Span struct {
+	Line, Column       int
+	EndLine, EndColumn int
+}
+
+// Diagnostic is a finding about the source that doesn't come from running it,
+// such as a lint warning.
+type Diagnostic struct {
+	Severity Severity
+	Span     Span
+	Where    string
+	// Rule names the check that produced the diagnostic, if any
+	Rule    string
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	whereStr := ""
+	if d.Where != "" {
+		whereStr = fmt.Sprintf(" at '%s'", d.Where)
+	}
+
+	ruleStr := ""
+	if d.Rule != "" {
+		ruleStr = fmt.Sprintf(" [%s]", d.Rule)
+	}
+
+	return fmt.Sprintf("[line %d] %s%s: %s%s", d.Span.Line, d.Severity, whereStr, d.Message, ruleStr)
+}
@@ -11,14 +11,19 @@ var HadRuntimeError bool
 
 type ErrorReporter struct {
 	writer io.Writer
+	// a silent reporter leaves HadError alone, its caller collects the errors
+	silent bool
 }
 
+// NewErrorReporter returns a reporter writing to w. A reporter writing to io.Discard
+// is silent: the errors don't set HadError either, they are for its caller to collect,
+// from Scanner.Errors for instance.
 func NewErrorReporter(w io.Writer) *ErrorReporter {
 	// defaultly write to the standard error output;
 	if w == nil {
 		w = os.Stderr
 	}
-	return &ErrorReporter{writer: w}
+	return &ErrorReporter{writer: w, silent: w == io.Discard}
 }
 
 // Default reporter writes to stderr
@@ -27,19 +32,19 @@ var DefaultReporter = NewErrorReporter(os.Stderr)
 // ScanError reports errors from the scanner
 func (e *ErrorReporter) ScanError(line int, message string) {
 	e.report(line, "", message, "ScanError")
-	HadError = true
+	HadError = HadError || !e.silent
 }
 
 // ParseError reports errors from the parser
 func (e *ErrorReporter) ParseError(line int, where, message string) {
 	e.report(line, where, message, "ParseError")
-	HadError = true
+	HadError = HadError || !e.silent
 }
 
 // RuntimeError reports errors from the interpreter
 func (e *ErrorReporter) RuntimeError(line int, message string) {
 	e.report(line, "", message, "RuntimeError")
-	HadRuntimeError = true
+	HadRuntimeError = HadRuntimeError || !e.silent
 }
 
 func (e *ErrorReporter) report(line int, where, message, errorType string) {
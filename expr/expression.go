@@ -11,8 +11,11 @@ type Visitor[T any] interface {
 	VisitLiteral(literal *Literal[T]) T
 	VisitVariable(variable *Variable[T]) T
 	VisitAssignment(assignment *Assignment[T]) T
+	VisitCompoundAssignment(assignment *CompoundAssignment[T]) T
 	VisitLogical(logical *Logical[T]) T
+	VisitConditional(conditional *Conditional[T]) T
 	VisitCall(callee *Call[T]) T
+	VisitInterpolation(interpolation *Interpolation[T]) T
 }
 
 type Expression[T any] interface {
@@ -56,6 +59,27 @@ func (logical *Logical[T]) Accept(visitor Visitor[T]) T {
 	return visitor.VisitLogical(logical)
 }
 
+// conditional node, condition ? then : else evaluates only one of its branches
+type Conditional[T any] struct {
+	Condition Expression[T]
+	Question  token.Token
+	Then      Expression[T]
+	Else      Expression[T]
+}
+
+func NewConditional[T any](condition Expression[T], question token.Token, then, otherwise Expression[T]) Expression[T] {
+	return &Conditional[T]{
+		Condition: condition,
+		Question:  question,
+		Then:      then,
+		Else:      otherwise,
+	}
+}
+
+func (conditional *Conditional[T]) Accept(visitor Visitor[T]) T {
+	return visitor.VisitConditional(conditional)
+}
+
 // binary node
 type Binary[T any] struct {
 	Left     Expression[T]
@@ -146,3 +170,46 @@ func NewAssignment[T any](tok token.Token, exp Expression[T]) Expression[T] {
 func (a *Assignment[T]) Accept(visitor Visitor[T]) T {
 	return visitor.VisitAssignment(a)
 }
+
+// the compound assignment node, an operator applied to a variable and the result stored
+// back in it: a += b, and ++a or a++ which add 1 to a. Value is nil for ++ and --, Postfix
+// is set for a++ and a--, they evaluate to the value the variable had before.
+type CompoundAssignment[T any] struct {
+	Name     token.Token
+	Operator token.Token
+	Value    Expression[T]
+	Postfix  bool
+}
+
+func NewCompoundAssignment[T any](name token.Token, operator token.Token, value Expression[T], postfix bool) Expression[T] {
+	return &CompoundAssignment[T]{
+		Name:     name,
+		Operator: operator,
+		Value:    value,
+		Postfix:  postfix,
+	}
+}
+
+func (a *CompoundAssignment[T]) Accept(visitor Visitor[T]) T {
+	return visitor.VisitCompoundAssignment(a)
+}
+
+// interpolation node, a string with embedded expressions. The text around them is
+// held by Literal parts, Start and End are the first and last tokens of the string.
+type Interpolation[T any] struct {
+	Start token.Token
+	Parts []Expression[T]
+	End   token.Token
+}
+
+func NewInterpolation[T any](start token.Token, parts []Expression[T], end token.Token) Expression[T] {
+	return &Interpolation[T]{
+		Start: start,
+		Parts: parts,
+		End:   end,
+	}
+}
+
+func (interpolation *Interpolation[T]) Accept(visitor Visitor[T]) T {
+	return visitor.VisitInterpolation(interpolation)
+}
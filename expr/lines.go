@@ -0,0 +1,107 @@
+package expr
+
+import "golox/token"
+
+// Lines returns the first and last source lines spanned by the tokens of e,
+// both are 0 when e holds no token, like a bare literal.
+func Lines[T any](e Expression[T]) (first, last int) {
+	finder := &lineFinder[T]{}
+	finder.visit(e)
+	return finder.first, finder.last
+}
+
+type lineFinder[T any] struct {
+	first, last int
+	zero        T
+}
+
+func (f *lineFinder[T]) visit(e Expression[T]) {
+	if e != nil {
+		e.Accept(f)
+	}
+}
+
+func (f *lineFinder[T]) token(tok token.Token) {
+	if tok.Line == 0 {
+		return
+	}
+	if f.first == 0 || tok.Line < f.first {
+		f.first = tok.Line
+	}
+	if tok.Line > f.last {
+		f.last = tok.Line
+	}
+}
+
+func (f *lineFinder[T]) VisitBinary(binary *Binary[T]) T {
+	f.visit(binary.Left)
+	f.token(*binary.Operator)
+	f.visit(binary.Right)
+	return f.zero
+}
+
+func (f *lineFinder[T]) VisitUnary(unary *Unary[T]) T {
+	f.token(*unary.Operator)
+	f.visit(unary.Right)
+	return f.zero
+}
+
+func (f *lineFinder[T]) VisitGrouping(grouping *Grouping[T]) T {
+	f.visit(grouping.Expression)
+	return f.zero
+}
+
+func (f *lineFinder[T]) VisitLiteral(literal *Literal[T]) T {
+	return f.zero
+}
+
+func (f *lineFinder[T]) VisitVariable(variable *Variable[T]) T {
+	f.token(variable.Name)
+	return f.zero
+}
+
+func (f *lineFinder[T]) VisitAssignment(assignment *Assignment[T]) T {
+	f.token(assignment.Tok)
+	f.visit(assignment.Exp)
+	return f.zero
+}
+
+func (f *lineFinder[T]) VisitCompoundAssignment(assignment *CompoundAssignment[T]) T {
+	f.token(assignment.Name)
+	f.token(assignment.Operator)
+	f.visit(assignment.Value)
+	return f.zero
+}
+
+func (f *lineFinder[T]) VisitLogical(logical *Logical[T]) T {
+	f.visit(logical.Left)
+	f.token(logical.Operator)
+	f.visit(logical.Right)
+	return f.zero
+}
+
+func (f *lineFinder[T]) VisitConditional(conditional *Conditional[T]) T {
+	f.visit(conditional.Condition)
+	f.token(conditional.Question)
+	f.visit(conditional.Then)
+	f.visit(conditional.Else)
+	return f.zero
+}
+
+func (f *lineFinder[T]) VisitCall(call *Call[T]) T {
+	f.visit(call.Calleee)
+	f.token(call.OpeningParen)
+	for _, argument := range call.Arguments {
+		f.visit(argument)
+	}
+	return f.zero
+}
+
+func (f *lineFinder[T]) VisitInterpolation(interpolation *Interpolation[T]) T {
+	f.token(interpolation.Start)
+	for _, part := range interpolation.Parts {
+		f.visit(part)
+	}
+	f.token(interpolation.End)
+	return f.zero
+}
@@ -0,0 +1,347 @@
+package format
+
+import (
+	goerrors "errors"
+	"golox/parser"
+	"golox/scanner"
+	"golox/token"
+	"strings"
+)
+
+// Source reformats a Lox program in the canonical style.
+//
+// The program is parsed first so that only valid code is touched, then its token
+// stream (comments included) is printed again with normalized spacing, one
+// statement per line, two space indentation and opening braces on the same line.
+func Source(source string) (string, error) {
+	s := scanner.NewScanner(source)
+	s.KeepComments = true
+	tokens := s.ScanTokens()
+	if s.HadError() {
+		return "", goerrors.New("source has scan errors")
+	}
+
+	var code []token.Token
+	for _, tok := range tokens {
+		if tok.TokenType != token.COMMENT {
+			code = append(code, tok)
+		}
+	}
+	if _, errs := parser.NewParser(code).Parse(); errs != nil {
+		return "", goerrors.Join(errs...)
+	}
+
+	p := &printer{tokens: tokens}
+	return p.print(), nil
+}
+
+// the construct owning a pending statement body
+type owner int
+
+const (
+	ownerBlock owner = iota
+	ownerIf
+	ownerElse
+	ownerLoop
+)
+
+// body is an unbraced statement body, indented one level until the statement ends
+type body struct {
+	owner owner
+	depth int
+}
+
+type printer struct {
+	tokens []token.Token
+	index  int
+
+	out  strings.Builder
+	line strings.Builder
+
+	braces []owner
+	bodies []body
+
+	parenDepth  int
+	header      owner // construct whose parenthesized header is being printed
+	headerDepth int   // paren depth the header started at, -1 outside headers
+	inHeader    bool
+	braceOwner  owner // owner of the next '{'
+
+	prev         *token.Token // last printed token, comments excluded
+	prevUnary    bool
+	afterComment bool
+	prevEndLine  int
+	needNewline  bool
+	lastOpen     bool // the last finished line ended with '{'
+}
+
+func (p *printer) print() string {
+	p.braceOwner = ownerBlock
+
+	for ; p.index < len(p.tokens); p.index++ {
+		tok := p.tokens[p.index]
+		if tok.TokenType == token.EOF {
+			break
+		}
+
+		if tok.TokenType == token.COMMENT {
+			p.comment(tok)
+		} else {
+			p.token(tok)
+		}
+		p.prevEndLine = tok.Line
+	}
+
+	p.flush()
+	return p.out.String()
+}
+
+// next returns the type of the next non-comment token.
+func (p *printer) next() token.TokenType {
+	for _, tok := range p.tokens[p.index+1:] {
+		if tok.TokenType != token.COMMENT {
+			return tok.TokenType
+		}
+	}
+	return token.EOF
+}
+
+func startLine(tok token.Token) int {
+	return tok.Line - strings.Count(tok.Lexeme, "\n")
+}
+
+func (p *printer) indent() int {
+	return len(p.braces) + len(p.bodies)
+}
+
+// flush terminates the current output line.
+func (p *printer) flush() {
+	if p.line.Len() == 0 {
+		return
+	}
+	text := p.line.String()
+	p.out.WriteString(text)
+	p.out.WriteString("\n")
+	p.line.Reset()
+	p.lastOpen = strings.HasSuffix(text, "{")
+	p.needNewline = false
+}
+
+// begin moves to a new output line when one is due, preserving a single blank line from the source.
+func (p *printer) begin(tok token.Token) {
+	if p.needNewline {
+		p.flush()
+		if p.out.Len() > 0 && startLine(tok) > p.prevEndLine+1 && !p.lastOpen && tok.TokenType != token.RIGHT_BRACE {
+			p.out.WriteString("\n")
+		}
+	}
+}
+
+func (p *printer) write(text string, space bool) {
+	if p.line.Len() == 0 {
+		p.line.WriteString(strings.Repeat("  ", p.indent()))
+	} else if space {
+		p.line.WriteString(" ")
+	}
+	p.line.WriteString(text)
+}
+
+func (p *printer) comment(tok token.Token) {
+	trailing := p.line.Len() > 0 && startLine(tok) == p.prevEndLine
+	if !trailing {
+		p.needNewline = true
+		p.begin(tok)
+	}
+
+	p.write(tok.Lexeme, p.prev == nil || p.prev.TokenType != token.LEFT_PAREN || p.afterComment)
+	p.afterComment = true
+
+	// a line comment always ends the line, a block comment only when the code continues on a later line
+	if strings.HasPrefix(tok.Lexeme, "//") || p.nextStartLine() > tok.Line {
+		p.needNewline = true
+	}
+}
+
+func (p *printer) nextStartLine() int {
+	if p.index+1 < len(p.tokens) {
+		return startLine(p.tokens[p.index+1])
+	}
+	return p.tokens[p.index].Line
+}
+
+func (p *printer) token(tok token.Token) {
+	if tok.TokenType == token.RIGHT_BRACE {
+		p.needNewline = true
+		p.begin(tok)
+		p.flush()
+		p.closeBrace(tok)
+		return
+	}
+
+	p.begin(tok)
+	unary := p.isUnary(tok)
+	p.write(tok.Lexeme, p.space(tok))
+	p.prev, p.prevUnary, p.afterComment = &tok, unary, false
+
+	switch tok.TokenType {
+	case token.IF:
+		p.header, p.inHeader, p.headerDepth = ownerIf, true, -1
+	case token.WHILE, token.FOR:
+		p.header, p.inHeader, p.headerDepth = ownerLoop, true, -1
+
+	case token.ELSE:
+		switch p.next() {
+		case token.LEFT_BRACE:
+			p.braceOwner = ownerElse
+		case token.IF:
+		default:
+			p.openBody(ownerElse)
+		}
+
+	case token.LEFT_PAREN:
+		if p.inHeader && p.headerDepth < 0 {
+			p.headerDepth = p.parenDepth
+		}
+		p.parenDepth++
+
+	case token.RIGHT_PAREN:
+		p.parenDepth--
+		if p.inHeader && p.parenDepth == p.headerDepth {
+			p.inHeader = false
+			if p.next() == token.LEFT_BRACE {
+				p.braceOwner = p.header
+			} else {
+				p.openBody(p.header)
+			}
+		}
+
+	case token.LEFT_BRACE:
+		p.braces = append(p.braces, p.braceOwner)
+		p.braceOwner = ownerBlock
+		p.needNewline = true
+
+	case token.SEMICOLON:
+		if p.parenDepth == 0 {
+			p.needNewline = true
+			p.endStatement()
+		}
+	}
+}
+
+func (p *printer) openBody(o owner) {
+	p.bodies = append(p.bodies, body{owner: o, depth: len(p.braces)})
+	p.needNewline = true
+}
+
+func (p *printer) closeBrace(tok token.Token) {
+	o := ownerBlock
+	if len(p.braces) > 0 {
+		o = p.braces[len(p.braces)-1]
+		p.braces = p.braces[:len(p.braces)-1]
+	}
+
+	p.write(tok.Lexeme, false)
+	p.prev, p.prevUnary, p.afterComment = &tok, false, false
+
+	// "} else" stays on one line
+	if o == ownerIf && p.next() == token.ELSE {
+		return
+	}
+	p.needNewline = true
+	p.endStatement()
+}
+
+// endStatement closes every unbraced body that ended with the statement just printed.
+func (p *printer) endStatement() {
+	for len(p.bodies) > 0 {
+		top := p.bodies[len(p.bodies)-1]
+		if top.depth != len(p.braces) {
+			return
+		}
+
+		p.bodies = p.bodies[:len(p.bodies)-1]
+		if top.owner == ownerIf && p.next() == token.ELSE {
+			return
+		}
+	}
+}
+
+// tokens that can end an operand, anything following them is a binary operator or a call
+func endsOperand(tok *token.Token) bool {
+	if tok == nil {
+		return false
+	}
+	switch tok.TokenType {
+	case token.IDENTIFIER, token.NUMBER, token.STRING, token.TRUE, token.FALSE,
+		token.NIL, token.THIS, token.SUPER, token.RIGHT_PAREN:
+		return true
+	}
+	return false
+}
+
+// continuesString reports whether tok is a part of an interpolated string following an expression.
+func continuesString(tok token.Token) bool {
+	return (tok.TokenType == token.STRING || tok.TokenType == token.INTERPOLATION) && strings.HasPrefix(tok.Lexeme, "}")
+}
+
+func (p *printer) isUnary(tok token.Token) bool {
+	switch tok.TokenType {
+	case token.BANG, token.TILDE:
+		return true
+	case token.MINUS, token.PLUS_PLUS, token.MINUS_MINUS:
+		return !p.prevEndsOperand()
+	}
+	return false
+}
+
+// prevEndsOperand is endsOperand for the previous token, which can also be a postfix ++ or --.
+func (p *printer) prevEndsOperand() bool {
+	switch {
+	case endsOperand(p.prev):
+		return true
+	case p.prev == nil:
+		return false
+	case p.prev.TokenType == token.PLUS_PLUS, p.prev.TokenType == token.MINUS_MINUS:
+		return !p.prevUnary
+	}
+	return false
+}
+
+// space reports whether tok is separated from the previous token on the same line.
+func (p *printer) space(tok token.Token) bool {
+	if p.prev == nil || p.afterComment {
+		return true
+	}
+
+	// - -a and + +a would scan as the increment and decrement operators once joined
+	if p.prev.TokenType == token.MINUS && (tok.TokenType == token.MINUS || tok.TokenType == token.MINUS_MINUS) ||
+		p.prev.TokenType == token.PLUS && (tok.TokenType == token.PLUS || tok.TokenType == token.PLUS_PLUS) {
+		return true
+	}
+
+	// the expressions of an interpolated string are printed tight against its parts
+	if p.prev.TokenType == token.INTERPOLATION || continuesString(tok) {
+		return false
+	}
+
+	switch tok.TokenType {
+	case token.RIGHT_PAREN, token.COMMA, token.SEMICOLON, token.DOT:
+		return false
+	case token.LEFT_PAREN:
+		if endsOperand(p.prev) {
+			return false
+		}
+	case token.PLUS_PLUS, token.MINUS_MINUS:
+		// a postfix operator sticks to its variable
+		if endsOperand(p.prev) {
+			return false
+		}
+	}
+
+	switch p.prev.TokenType {
+	case token.LEFT_PAREN, token.DOT:
+		return false
+	}
+
+	return !p.prevUnary
+}
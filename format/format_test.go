@@ -0,0 +1,123 @@
+package format
+
+import (
+	"golox/loxtest"
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "spacing",
+			source: "var a=1;print  a+-a*(2 -clock( ));",
+			want:   "var a = 1;\nprint a + -a * (2 - clock());\n",
+		},
+		{
+			name:   "blocks and blank lines",
+			source: "{ var a = !true;\n\n\n  print a; }",
+			want:   "{\n  var a = !true;\n\n  print a;\n}\n",
+		},
+		{
+			name:   "unbraced bodies",
+			source: "if (a) if (b) print 1; else print 2; while(a) a = false;",
+			want:   "if (a)\n  if (b)\n    print 1;\n  else\n    print 2;\nwhile (a)\n  a = false;\n",
+		},
+		{
+			name:   "else chains",
+			source: "if (a) { print 1; } else if (b) print 2; else { print 3; }",
+			want:   "if (a) {\n  print 1;\n} else if (b)\n  print 2;\nelse {\n  print 3;\n}\n",
+		},
+		{
+			name:   "for header",
+			source: "for(var i=0;i<3;i=i+1){print i;}\nfor (;;) {}",
+			want:   "for (var i = 0; i < 3; i = i + 1) {\n  print i;\n}\nfor (;;) {\n}\n",
+		},
+		{
+			name:   "comments",
+			source: "// header\nvar a = 1;   // trailing\n{\n/* inside */\nprint a /* inline */ + 1;\n}",
+			want:   "// header\nvar a = 1; // trailing\n{\n  /* inside */\n  print a /* inline */ + 1;\n}\n",
+		},
+		{
+			name:   "leading unary operator",
+			source: "-x;\n++a;",
+			want:   "-x;\n++a;\n",
+		},
+		{
+			name:   "increments and compound assignments",
+			source: "a+=1;b ++;-- c;print a++ +  ++b-c--;a%=b ~/ 2;",
+			want:   "a += 1;\nb++;\n--c;\nprint a++ + ++b - c--;\na %= b ~/ 2;\n",
+		},
+		{
+			name:   "repeated signs",
+			source: "print - -a;print -(--a);print a - --b;print a+ ++b;",
+			want:   "print - -a;\nprint -(--a);\nprint a - --b;\nprint a + ++b;\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Source(tt.source)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+
+			again, err := Source(got)
+			if err != nil {
+				t.Fatalf("unexpected error reformatting: %v", err)
+			}
+			if again != got {
+				t.Errorf("formatting is not idempotent, second pass gave:\n%s", again)
+			}
+		})
+	}
+}
+
+func TestFormattingKeepsBehavior(t *testing.T) {
+	source := "var a = 1;\nprint - -a;\nprint a - -a;\nprint a- --a;\n"
+	formatted, err := Source(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before, after := loxtest.Run(source, nil), loxtest.Run(formatted, nil)
+	if before.CompileErrors != nil || after.CompileErrors != nil || !slices.Equal(before.Output, after.Output) {
+		t.Errorf("%q printed %v %v, formatted as %q it printed %v %v",
+			source, before.Output, before.CompileErrors, formatted, after.Output, after.CompileErrors)
+	}
+}
+
+func TestSourceRejectsInvalidCode(t *testing.T) {
+	if _, err := Source("print (1;"); err == nil {
+		t.Error("expected a parse error")
+	}
+}
+
+func TestExampleScriptsFormatStably(t *testing.T) {
+	for _, path := range []string{"../hello.golox", "../test.golox"} {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		once, err := Source(string(source))
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		twice, err := Source(once)
+		if err != nil {
+			t.Fatalf("%s formatted: %v", path, err)
+		}
+		if twice != once {
+			t.Errorf("%s: formatting again changed\n%s\ninto\n%s", path, once, twice)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package interpreter
+
+import "golox/number"
+
+// decimalFunction is decimal(value), it converts a number or the text of one to a decimal.
+type decimalFunction struct{}
+
+func (d *decimalFunction) Arity() int { return 1 }
+
+func (d *decimalFunction) Call(interpreter *Interpreter, arguments []any) any {
+	switch value := arguments[0].(type) {
+	case string:
+		decimal, err := number.ParseDecimal(value)
+		if err == number.ErrDecimalScale {
+			panic(interpreter.nativeError("%s.", err))
+		}
+		if err != nil {
+			panic(interpreter.nativeError("Invalid decimal %q.", value))
+		}
+		return decimal
+	default:
+		if !number.IsNumber(value) {
+			panic(interpreter.nativeError("Argument to decimal must be a number or a string."))
+		}
+		if decimal, ok := number.ToDecimal(value); ok {
+			return decimal
+		}
+		panic(interpreter.nativeError("Can't convert %s to a decimal.", number.Format(value)))
+	}
+}
+
+func (d *decimalFunction) String() string { return "<native fn>" }
+
+// the most digits a quotient is computed to, past it a division could exhaust the memory
+const maxDecimalPrecision = 1000
+
+// decimalPrecision is decimalPrecision(digits), it sets the significant digits of inexact decimal quotients.
+type decimalPrecision struct{}
+
+func (d *decimalPrecision) Arity() int { return 1 }
+
+func (d *decimalPrecision) Call(interpreter *Interpreter, arguments []any) any {
+	digits, ok := arguments[0].(int64)
+	if !ok || digits < 1 || digits > maxDecimalPrecision {
+		panic(interpreter.nativeError("Decimal precision must be an integer from 1 to %d.", maxDecimalPrecision))
+	}
+	interpreter.decimals.Precision = int(digits)
+	return nil
+}
+
+func (d *decimalPrecision) String() string { return "<native fn>" }
+
+// decimalRounding is decimalRounding(mode), it sets how inexact decimal quotients are rounded.
+type decimalRounding struct{}
+
+func (d *decimalRounding) Arity() int { return 1 }
+
+func (d *decimalRounding) Call(interpreter *Interpreter, arguments []any) any {
+	name, _ := arguments[0].(string)
+	rounding, ok := number.ParseRounding(name)
+	if !ok {
+		panic(interpreter.nativeError("Unknown rounding mode %s, expected half_even, half_up, half_down, up, down, ceiling or floor.", interpreter.quote(arguments[0])))
+	}
+	interpreter.decimals.Rounding = rounding
+	return nil
+}
+
+func (d *decimalRounding) String() string { return "<native fn>" }
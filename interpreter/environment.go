@@ -51,3 +51,28 @@ func (e *Environment) Assign(name string, value any) error {
 
 	return fmt.Errorf("undefined variable '%s'", name)
 }
+
+// Names returns the names defined in this scope and every enclosing one.
+func (e *Environment) Names() []string {
+	var names []string
+	for env := e; env != nil; env = env.parent {
+		for name := range env.values {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Parent returns the enclosing environment, nil for the global one.
+func (e *Environment) Parent() *Environment {
+	return e.parent
+}
+
+// Values returns a copy of the variables defined in this scope only.
+func (e *Environment) Values() map[string]any {
+	values := make(map[string]any, len(e.values))
+	for name, value := range e.values {
+		values[name] = value
+	}
+	return values
+}
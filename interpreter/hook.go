@@ -0,0 +1,62 @@
+package interpreter
+
+import (
+	"golox/expr"
+	"golox/stmt"
+	"golox/token"
+)
+
+// Hook observes a running program, it is how tools such as the debugger follow the execution.
+type Hook interface {
+	// BeforeExecute is called before every statement runs, with the interpreter
+	// in the state the statement runs in.
+	BeforeExecute(i *Interpreter, st stmt.Statement[any])
+}
+
+// ExecuteHook is implemented by hooks that also need to know when a statement completes, profilers for instance.
+type ExecuteHook interface {
+	// AfterExecute is called once a statement ran, or when a runtime error interrupted it.
+	AfterExecute(i *Interpreter, st stmt.Statement[any])
+}
+
+// CallHook is implemented by hooks that observe calls.
+type CallHook interface {
+	// BeforeCall is called once the arguments are evaluated, call is the innermost of Calls.
+	BeforeCall(i *Interpreter, call Call)
+	// AfterCall is called once the call returned, or when a runtime error interrupted it.
+	AfterCall(i *Interpreter, call Call)
+}
+
+// BranchHook is implemented by hooks that observe the way conditions go, coverage for instance.
+type BranchHook interface {
+	// IfBranch is called once the condition of an if statement is evaluated,
+	// then reports whether the then branch runs.
+	IfBranch(i *Interpreter, st *stmt.IfStmt[any], then bool)
+	// LogicalBranch is called once the left operand of a logical expression is
+	// evaluated, right reports whether the right operand is evaluated too.
+	LogicalBranch(i *Interpreter, e *expr.Logical[any], right bool)
+	// ConditionalBranch is called once the condition of a conditional expression
+	// is evaluated, then reports whether the then branch is evaluated.
+	ConditionalBranch(i *Interpreter, e *expr.Conditional[any], then bool)
+}
+
+// DefineHook is implemented by hooks that also observe variable declarations.
+type DefineHook interface {
+	// AfterDefine is called once a declaration stored the initial value of its variable.
+	AfterDefine(i *Interpreter, name token.Token, value any)
+}
+
+// AssignHook is implemented by hooks that also observe assignments, watchpoints for instance.
+type AssignHook interface {
+	// AfterAssign is called once an assignment stored its value, old is the value it replaced.
+	AfterAssign(i *Interpreter, name token.Token, old, value any)
+}
+
+// Call is a function call in progress.
+type Call struct {
+	Name string
+	// Paren is the closing parenthesis of the call, locating it in the source
+	Paren token.Token
+	// Environment is the caller's environment, the one it resumes in when the call returns
+	Environment *Environment
+}
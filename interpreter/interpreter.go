@@ -4,23 +4,36 @@ import (
 	"fmt"
 	"golox/errors"
 	"golox/expr"
+	"golox/number"
 	"golox/stmt"
 	"golox/token"
+	"io"
+	"os"
+	"strconv"
+	"strings"
 )
 
-type runtimeError struct {
-	tok     token.Token
-	message string
+// RuntimeError is the error that stops a program while it runs.
+type RuntimeError struct {
+	Token   token.Token
+	Message string
 }
 
-func (r runtimeError) Error() string {
-	return fmt.Sprintf("[line %d] RuntimeError: %s", r.tok.Line, r.message)
+func (r RuntimeError) Error() string {
+	return fmt.Sprintf("[line %d] RuntimeError: %s", r.Token.Line, r.Message)
 }
 
 type Interpreter struct {
 	globals     *Environment
 	environment *Environment
+	calls       []Call
 	IsRepl bool
+	// Out receives the printed values and the runtime errors, os.Stdout by default
+	Out io.Writer
+	// Hook, when set, is called before every statement
+	Hook Hook
+	// the division settings of decimals, changed by decimalPrecision and decimalRounding
+	decimals number.Context
 }
 
 func NewInterpreter() *Interpreter {
@@ -28,36 +41,147 @@ func NewInterpreter() *Interpreter {
 
 	// add the clock function to the global scope;
 	gl.Define("clock", NewClock())
+	gl.Define("decimal", &decimalFunction{})
+	gl.Define("decimalPrecision", &decimalPrecision{})
+	gl.Define("decimalRounding", &decimalRounding{})
 
 	return &Interpreter{
 		globals:     gl,
 		environment: NewEnclosedEnvironment(gl),
 		IsRepl: false,
+		Out:         os.Stdout,
+		decimals:    number.DefaultContext,
 	}
 }
 
+// Builtins returns the names predefined in the global scope.
+func Builtins() []string {
+	return NewInterpreter().globals.Names()
+}
+
+// Names returns every variable name visible from the current scope.
+func (i *Interpreter) Names() []string {
+	return i.environment.Names()
+}
+
+// Globals returns the environment holding the builtins, the script runs in one enclosed by it.
+func (i *Interpreter) Globals() *Environment {
+	return i.globals
+}
+
+// Environment returns the environment of the innermost scope being executed.
+func (i *Interpreter) Environment() *Environment {
+	return i.environment
+}
+
+// Calls returns the calls in progress, the innermost last.
+func (i *Interpreter) Calls() []Call {
+	return i.calls
+}
+
+// Evaluate evaluates an expression in env, a runtime error is returned rather than reported.
+func (i *Interpreter) Evaluate(e expr.Expression[any], env *Environment) (value any, err error) {
+	previousEnv := i.environment
+	i.environment = env
+
+	defer func() {
+		i.environment = previousEnv
+		if r := recover(); r != nil {
+			rerr, ok := r.(RuntimeError)
+			if !ok {
+				panic(r)
+			}
+			err = rerr
+		}
+	}()
+
+	return i.evaluate(e), nil
+}
+
+// Stringify formats a value the way print does.
+func (i *Interpreter) Stringify(value any) string {
+	return i.stringify(value)
+}
+
+// IsEqual compares two values the way == does, numbers by value.
+func (i *Interpreter) IsEqual(a, b any) bool {
+	return i.isEqual(a, b)
+}
+
 func (i *Interpreter) Interpret(stmts []stmt.Statement[any]) {
+	if err := i.Run(stmts); err != nil {
+		fmt.Fprintln(i.Out, err.Error())
+		errors.HadRuntimeError = true
+	}
+}
+
+// Run executes statements like Interpret, but returns the RuntimeError that
+// stopped them rather than reporting it.
+func (i *Interpreter) Run(stmts []stmt.Statement[any]) (err error) {
+	previousEnv := i.environment
+
 	defer func() {
 		if r := recover(); r != nil {
 
-			// cast the error from recover to runtimeError;
-			if rerr, ok := r.(runtimeError); ok {
-				fmt.Println(rerr.Error())
-				errors.HadRuntimeError = true
-			} else {
+			// cast the error from recover to RuntimeError;
+			rerr, ok := r.(RuntimeError)
+			if !ok {
 				// This is a programming error, not a Lox runtime error
 				panic(r)
 			}
+
+			// the blocks the error left didn't restore their enclosing environment
+			i.environment = previousEnv
+			err = rerr
 		}
 	}()
 
+	// calls interrupted by an earlier runtime error are gone
+	i.calls = i.calls[:0]
+
 	for _, st := range stmts {
 
 		i.execute(st)
 	}
+	return nil
+}
+
+// TestScope is the state the test blocks of a script start from: a copy of the
+// script's variables and decimal settings, taken once the script ran.
+type TestScope struct {
+	values   map[string]any
+	decimals number.Context
+}
+
+// TestScope copies the script's variables, the values of Lox are immutable so
+// an assignment in a test can't reach the copy.
+func (i *Interpreter) TestScope() TestScope {
+	return TestScope{values: i.environment.Values(), decimals: i.decimals}
+}
+
+// RunTest executes the body of a test block like Run, in a fresh environment
+// enclosed by the globals and holding the variables of scope. Every test starts
+// from the same state, whatever the tests before it changed.
+func (i *Interpreter) RunTest(test *stmt.TestStmt[any], scope TestScope) error {
+	env := NewEnclosedEnvironment(i.globals)
+	for name, value := range scope.values {
+		env.Define(name, value)
+	}
+
+	previousEnv, previousDecimals := i.environment, i.decimals
+	i.environment, i.decimals = env, scope.decimals
+	defer func() { i.environment, i.decimals = previousEnv, previousDecimals }()
+
+	return i.Run([]stmt.Statement[any]{test.Body})
 }
 
 func (i *Interpreter) execute(st stmt.Statement[any]) {
+	if i.Hook != nil {
+		i.Hook.BeforeExecute(i, st)
+		if hook, ok := i.Hook.(ExecuteHook); ok {
+			defer hook.AfterExecute(i, st)
+		}
+	}
 	st.Accept(i)
 }
 
@@ -69,24 +193,52 @@ func (i *Interpreter) VisitWhileStmt(w *stmt.WhileStmt[any]) {
 }
 
 func (i *Interpreter) VisitIfStmt(is *stmt.IfStmt[any]) {
-	if i.isTruthy(i.evaluate(is.Condition)) {
+	then := i.isTruthy(i.evaluate(is.Condition))
+	if hook, ok := i.Hook.(BranchHook); ok {
+		hook.IfBranch(i, is, then)
+	}
+
+	if then {
 		i.execute(is.ThenBranch)
 	} else if is.ElseBranch != nil {
 		i.execute(is.ElseBranch)
 	}
 }
 
+// test blocks only run through RunTest
+func (i *Interpreter) VisitTestStmt(t *stmt.TestStmt[any]) {}
+
+// benchmarks only run through golox bench
+func (i *Interpreter) VisitBenchStmt(b *stmt.BenchStmt[any]) {}
+
+func (i *Interpreter) VisitAssertStmt(a *stmt.AssertStmt[any]) {
+	// the operands of a comparison are shown in the failure message
+	if b, ok := a.Expr.(*expr.Binary[any]); ok {
+		left := i.evaluate(b.Left)
+		right := i.evaluate(b.Right)
+		if !i.isTruthy(i.binary(*b.Operator, left, right)) {
+			panic(i.error(a.Keyword, "Assertion failed: %s (got %s %s %s)",
+				a.Source, i.quote(left), b.Operator.Lexeme, i.quote(right)))
+		}
+		return
+	}
+
+	if value := i.evaluate(a.Expr); !i.isTruthy(value) {
+		panic(i.error(a.Keyword, "Assertion failed: %s (got %s)", a.Source, i.quote(value)))
+	}
+}
+
 func (i *Interpreter) VisitExpressionStmt(ep *stmt.ExpressionStmt[any]) {
 	value := i.evaluate(ep.Expr)
 
 	if i.IsRepl && value != nil {
-		fmt.Println(i.stringify(value))
+		fmt.Fprintln(i.Out, i.stringify(value))
 	}
 }
 
 func (i *Interpreter) VisitPrintStmt(ep *stmt.PrintStmt[any]) {
 	value := i.evaluate(ep.Expr)
-	fmt.Println(i.stringify(value))
+	fmt.Fprintln(i.Out, i.stringify(value))
 }
 
 func (i *Interpreter) VisitVarStmt(v *stmt.VarStmt[any]) {
@@ -95,6 +247,10 @@ func (i *Interpreter) VisitVarStmt(v *stmt.VarStmt[any]) {
 		value = i.evaluate(v.Initializer)
 	}
 	i.environment.Define(v.Name.Lexeme, value)
+
+	if hook, ok := i.Hook.(DefineHook); ok {
+		hook.AfterDefine(i, v.Name, value)
+	}
 }
 
 // expression visitor
@@ -117,25 +273,66 @@ func (i *Interpreter) VisitCall(call *expr.Call[any]) any {
 		panic(i.error(call.OpeningParen, "Expected %d arguments but got %d .", len(call.Arguments), function.Arity()))
 	}
 
-	return function.Call(i, arguments)
+	i.calls = append(i.calls, Call{Name: callName(call.Calleee), Paren: call.OpeningParen, Environment: i.environment})
+	if hook, ok := i.Hook.(CallHook); ok {
+		hook.BeforeCall(i, i.calls[len(i.calls)-1])
+		defer hook.AfterCall(i, i.calls[len(i.calls)-1])
+	}
+	result := function.Call(i, arguments)
+	i.calls = i.calls[:len(i.calls)-1]
+
+	return result
+}
+
+// VisitInterpolation joins the parts of the string, stringified like print does.
+func (i *Interpreter) VisitInterpolation(interpolation *expr.Interpolation[any]) any {
+	var builder strings.Builder
+	for _, part := range interpolation.Parts {
+		builder.WriteString(i.stringify(i.evaluate(part)))
+	}
+	return builder.String()
+}
+
+// callName names a call after the variable holding the callee.
+func callName(callee expr.Expression[any]) string {
+	if variable, ok := callee.(*expr.Variable[any]); ok {
+		return variable.Name.Lexeme
+	}
+	return "<anonymous>"
 }
 
 func (i *Interpreter) VisitLogical(logical *expr.Logical[any]) any {
 	left := i.evaluate(logical.Left)
 
-	if logical.Operator.TokenType == token.OR {
-		if i.isTruthy(left) {
-			return left
-		}
+	// the left operand decides when it isn't nil for ??, truthy for or, falsey for and
+	var decided bool
+	if logical.Operator.TokenType == token.QUESTION_QUESTION {
+		decided = left != nil
 	} else {
-		if !i.isTruthy(left) {
-			return left
-		}
+		decided = i.isTruthy(left) == (logical.Operator.TokenType == token.OR)
+	}
+	if hook, ok := i.Hook.(BranchHook); ok {
+		hook.LogicalBranch(i, logical, !decided)
 	}
 
+	if decided {
+		return left
+	}
 	return i.evaluate(logical.Right)
 }
 
+func (i *Interpreter) VisitConditional(conditional *expr.Conditional[any]) any {
+	then := i.isTruthy(i.evaluate(conditional.Condition))
+	if hook, ok := i.Hook.(BranchHook); ok {
+		hook.ConditionalBranch(i, conditional, then)
+	}
+
+	if then {
+		return i.evaluate(conditional.Then)
+	}
+	return i.evaluate(conditional.Else)
+}
+
 func (i *Interpreter) VisitVariable(v *expr.Variable[any]) any {
 
 	value, err := i.environment.Get(v.Name.Lexeme)
@@ -147,33 +344,51 @@ func (i *Interpreter) VisitVariable(v *expr.Variable[any]) any {
 }
 
 func (i *Interpreter) VisitBinary(b *expr.Binary[any]) any {
-	left := i.evaluate(b.Left)
-	right := i.evaluate(b.Right)
+	return i.binary(*b.Operator, i.evaluate(b.Left), i.evaluate(b.Right))
+}
 
-	switch b.Operator.TokenType {
+func (i *Interpreter) binary(operator token.Token, left, right any) any {
+	switch operator.TokenType {
 	// Arithmetic
 	case token.MINUS:
-		i.checkNumberOperands(left, right, *b.Operator)
-		return left.(float64) - right.(float64)
+		i.checkArithmeticOperands(left, right, operator)
+		return number.Subtract(left, right)
 
 	case token.SLASH:
-		i.checkNumberOperands(left, right, *b.Operator)
-		if right.(float64) == 0 {
-			panic(i.error(*b.Operator, "Division by zero"))
-		}
-		return left.(float64) / right.(float64)
+		i.checkArithmeticOperands(left, right, operator)
+		quotient, err := i.decimals.Divide(left, right)
+		i.checkError(err, operator)
+		return quotient
 
 	case token.STAR:
-		i.checkNumberOperands(left, right, *b.Operator)
-		return left.(float64) * right.(float64)
+		i.checkArithmeticOperands(left, right, operator)
+		product, err := number.Multiply(left, right)
+		i.checkError(err, operator)
+		return product
+
+	case token.PERCENT:
+		i.checkArithmeticOperands(left, right, operator)
+		remainder, err := number.Modulo(left, right)
+		i.checkError(err, operator)
+		return remainder
+
+	case token.TILDE_SLASH:
+		i.checkArithmeticOperands(left, right, operator)
+		quotient, err := number.FloorDivide(left, right)
+		i.checkError(err, operator)
+		return quotient
+
+	case token.STAR_STAR:
+		i.checkArithmeticOperands(left, right, operator)
+		power, err := i.decimals.Power(left, right)
+		i.checkError(err, operator)
+		return power
 
 	case token.PLUS:
-		// TODO: apply the plus operator to numbers and string
 		// Handle number + number
-		if l, ok := left.(float64); ok {
-			if r, ok := right.(float64); ok {
-				return l + r
-			}
+		if number.IsNumber(left) && number.IsNumber(right) {
+			i.checkArithmeticOperands(left, right, operator)
+			return number.Add(left, right)
 		}
 		// Handle string + string
 		if l, ok := left.(string); ok {
@@ -181,24 +396,49 @@ func (i *Interpreter) VisitBinary(b *expr.Binary[any]) any {
 				return l + r
 			}
 		}
-		panic(i.error(*b.Operator, "Operands must be two numbers or two strings"))
-
-	// Comparison
+		panic(i.error(operator, "Operands must be two numbers or two strings"))
+
+	// Bitwise
+	case token.AMPERSAND:
+		i.checkIntegerOperands(left, right, operator)
+		return number.And(left, right)
+
+	case token.PIPE:
+		i.checkIntegerOperands(left, right, operator)
+		return number.Or(left, right)
+
+	case token.CARET:
+		i.checkIntegerOperands(left, right, operator)
+		return number.Xor(left, right)
+
+	case token.LESS_LESS:
+		i.checkIntegerOperands(left, right, operator)
+		shifted, err := number.ShiftLeft(left, right)
+		i.checkError(err, operator)
+		return shifted
+
+	case token.GREATER_GREATER:
+		i.checkIntegerOperands(left, right, operator)
+		shifted, err := number.ShiftRight(left, right)
+		i.checkError(err, operator)
+		return shifted
+
+	// Comparison, NaN is neither less nor greater than any number
 	case token.GREATER:
-		i.checkNumberOperands(left, right, *b.Operator)
-		return left.(float64) > right.(float64)
+		result, ordered := i.compare(left, right, operator)
+		return ordered && result > 0
 
 	case token.GREATER_EQUAL:
-		i.checkNumberOperands(left, right, *b.Operator)
-		return left.(float64) >= right.(float64)
+		result, ordered := i.compare(left, right, operator)
+		return ordered && result >= 0
 
 	case token.LESS:
-		i.checkNumberOperands(left, right, *b.Operator)
-		return left.(float64) < right.(float64)
+		result, ordered := i.compare(left, right, operator)
+		return ordered && result < 0
 
 	case token.LESS_EQUAL:
-		i.checkNumberOperands(left, right, *b.Operator)
-		return left.(float64) <= right.(float64)
+		result, ordered := i.compare(left, right, operator)
+		return ordered && result <= 0
 
 	// Equality
 	case token.BANG_EQUAL:
@@ -218,10 +458,14 @@ func (i *Interpreter) VisitUnary(u *expr.Unary[any]) any {
 	switch u.Operator.TokenType {
 	case token.MINUS:
 		i.checkNumberOperand(right, *u.Operator)
-		return -right.(float64)
+		return number.Negate(right)
 
 	case token.BANG:
 		return !i.isTruthy(right)
+
+	case token.TILDE:
+		i.checkIntegerOperand(right, *u.Operator)
+		return number.Not(right)
 	}
 
 	return nil
@@ -237,11 +481,66 @@ func (i *Interpreter) VisitLiteral(l *expr.Literal[any]) any {
 
 func (i *Interpreter) VisitAssignment(a *expr.Assignment[any]) any {
 	value := i.evaluate(a.Exp)
-	i.environment.Assign(a.Tok.Lexeme, value)
 
+	hook, watching := i.Hook.(AssignHook)
+	var old any
+	if watching {
+		old, _ = i.environment.Get(a.Tok.Lexeme)
+	}
+
+	i.assign(a.Tok, value)
+
+	if watching {
+		hook.AfterAssign(i, a.Tok, old, value)
+	}
 	return value
 }
 
+// the binary operators applied by the compound assignments
+var compoundOperators = map[token.TokenType]token.TokenType{
+	token.PLUS_EQUAL:    token.PLUS,
+	token.MINUS_EQUAL:   token.MINUS,
+	token.STAR_EQUAL:    token.STAR,
+	token.SLASH_EQUAL:   token.SLASH,
+	token.PERCENT_EQUAL: token.PERCENT,
+	token.PLUS_PLUS:     token.PLUS,
+	token.MINUS_MINUS:   token.MINUS,
+}
+
+// VisitCompoundAssignment reads the variable once, before the value is evaluated.
+func (i *Interpreter) VisitCompoundAssignment(a *expr.CompoundAssignment[any]) any {
+	old, err := i.environment.Get(a.Name.Lexeme)
+	if err != nil {
+		panic(i.error(a.Name, "%s", err.Error()))
+	}
+
+	var operand any = int64(1)
+	if a.Value != nil {
+		operand = i.evaluate(a.Value)
+	} else {
+		i.checkNumberOperand(old, a.Operator)
+	}
+
+	operator := a.Operator
+	operator.TokenType = compoundOperators[a.Operator.TokenType]
+	value := i.binary(operator, old, operand)
+	i.assign(a.Name, value)
+
+	if hook, ok := i.Hook.(AssignHook); ok {
+		hook.AfterAssign(i, a.Name, old, value)
+	}
+	if a.Postfix {
+		return old
+	}
+	return value
+}
+
+func (i *Interpreter) assign(name token.Token, value any) {
+	if err := i.environment.Assign(name.Lexeme, value); err != nil {
+		panic(i.error(name, "%s", err.Error()))
+	}
+}
+
 func (i *Interpreter) VisitBlockStmt(b *stmt.BlockStmt[any]) {
 	i.executeBlock(b.Stmts, NewEnclosedEnvironment(i.environment))
 }
@@ -279,32 +578,75 @@ func (i *Interpreter) isEqual(a, b any) bool {
 	if a == nil || b == nil {
 		return false
 	}
+	// numbers are equal by value whatever their representation, 1 == 1.0
+	if number.IsNumber(a) && number.IsNumber(b) {
+		return number.Equal(a, b)
+	}
 	return a == b
 }
 
-func (i *Interpreter) checkNumberOperands(left, right any, op token.Token) {
-	_, lok := left.(float64)
-	_, rok := right.(float64)
+func (i *Interpreter) compare(left, right any, op token.Token) (int, bool) {
+	i.checkNumberOperands(left, right, op)
+	return number.Compare(left, right)
+}
 
-	if lok && rok {
+func (i *Interpreter) checkNumberOperands(left, right any, op token.Token) {
+	if number.IsNumber(left) && number.IsNumber(right) {
 		return
 	}
 
 	panic(i.error(op, "Operands must be numbers"))
 }
 
+// checkArithmeticOperands checks the operands of an operator computing a number.
+func (i *Interpreter) checkArithmeticOperands(left, right any, op token.Token) {
+	i.checkNumberOperands(left, right, op)
+	if !number.CanMix(left, right) {
+		panic(i.error(op, "Operands can't mix a decimal and a float"))
+	}
+}
+
+func (i *Interpreter) checkIntegerOperands(left, right any, op token.Token) {
+	if number.IsInteger(left) && number.IsInteger(right) {
+		return
+	}
+	panic(i.error(op, "Operands must be integers"))
+}
+
+func (i *Interpreter) checkIntegerOperand(value any, op token.Token) {
+	if number.IsInteger(value) {
+		return
+	}
+	panic(i.error(op, "Operand must be an integer"))
+}
+
+// checkError reports the error of an operation of the number package.
+func (i *Interpreter) checkError(err error, op token.Token) {
+	if err != nil {
+		panic(i.error(op, "%s", err.Error()))
+	}
+}
+
 func (i *Interpreter) checkNumberOperand(value any, op token.Token) {
-	if _, ok := value.(float64); ok {
+	if number.IsNumber(value) {
 		return
 	}
 	panic(i.error(op, "Operand must be a number"))
 }
 
-func (i *Interpreter) error(tok token.Token, format string, args ...any) runtimeError {
-	return runtimeError{
-		tok:     tok,
-		message: fmt.Sprintf(format, args...),
+func (i *Interpreter) error(tok token.Token, format string, args ...any) RuntimeError {
+	return RuntimeError{
+		Token:   tok,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// quote stringifies a value, quoting strings so that "1" and 1 can be told apart.
+func (i *Interpreter) quote(value any) string {
+	if s, ok := value.(string); ok {
+		return strconv.Quote(s)
 	}
+	return i.stringify(value)
 }
 
 func (i *Interpreter) stringify(value any) string {
@@ -312,12 +654,14 @@ func (i *Interpreter) stringify(value any) string {
 		return "nil"
 	}
 
-	if num, ok := value.(float64); ok {
-		if num == float64(int64(num)) {
-			return fmt.Sprintf("%d", int64(num))
-		}
-		return fmt.Sprintf("%f", num)
+	if number.IsNumber(value) {
+		return number.Format(value)
 	}
 
 	return fmt.Sprintf("%v", value)
 }
+
+// nativeError is the error of a native function, reported on the call being made.
+func (i *Interpreter) nativeError(format string, args ...any) RuntimeError {
+	return i.error(i.calls[len(i.calls)-1].Paren, format, args...)
+}
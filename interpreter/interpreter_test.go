@@ -3,6 +3,8 @@ package interpreter
 import (
 	"golox/parser"
 	"golox/scanner"
+	"golox/stmt"
+	"strings"
 	"testing"
 )
 
@@ -92,26 +94,79 @@ func TestInterpreter(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// You'll need to capture output for proper testing
-			// For now, just check it doesn't panic
-			scanner := scanner.NewScanner(tt.source)
+			scanner := scanner.NewScanner("print " + tt.source + ";")
 			tokens := scanner.ScanTokens()
 			parser := parser.NewParser(tokens)
-			expr, err := parser.Parse()
+			statements, err := parser.Parse()
 
 			if err != nil {
 				t.Errorf("parse error: %v", err)
 				return
 			}
 
-			if expr == nil {
-				t.Error("got nil expression")
-				return
+			var output strings.Builder
+			interpreter := NewInterpreter()
+			interpreter.Out = &output
+			if err := interpreter.Run(statements); err != nil {
+				t.Fatalf("runtime error: %v", err)
 			}
 
-			// Would need to capture interpreter output to test properly
-			interpreter := NewInterpreter()
-			interpreter.Interpret(expr)
+			if got := strings.TrimSuffix(output.String(), "\n"); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
 		})
 	}
 }
+
+func TestRuntimeError(t *testing.T) {
+	statements, errs := parser.NewParser(scanner.NewScanner("print \"before\";\n{\n  var a = 1;\n  print -\"a\";\n}").ScanTokens()).Parse()
+	if errs != nil {
+		t.Fatalf("parse errors: %v", errs)
+	}
+
+	var output strings.Builder
+	interpreter := NewInterpreter()
+	interpreter.Out = &output
+	err := interpreter.Run(statements)
+
+	rerr, ok := err.(RuntimeError)
+	if !ok || rerr.Token.Line != 4 || rerr.Message != "Operand must be a number" {
+		t.Fatalf("got %v, want the error on line 4", err)
+	}
+	if output.String() != "before\n" {
+		t.Errorf("got output %q", output.String())
+	}
+
+	// the error leaves the block, its variables are gone
+	if _, err := interpreter.Environment().Get("a"); err == nil {
+		t.Error("still in the block after the error")
+	}
+}
+
+func TestRunTestRestoresState(t *testing.T) {
+	statements, errs := parser.NewParser(scanner.NewScanner("var n = 1;\ntest \"changes\" {\n  decimalPrecision(2);\n  var m = 2;\n}").ScanTokens()).Parse()
+	if errs != nil {
+		t.Fatalf("parse errors: %v", errs)
+	}
+
+	var output strings.Builder
+	interpreter := NewInterpreter()
+	interpreter.Out = &output
+	if err := interpreter.Run(statements); err != nil {
+		t.Fatalf("runtime error: %v", err)
+	}
+	if err := interpreter.RunTest(statements[1].(*stmt.TestStmt[any]), interpreter.TestScope()); err != nil {
+		t.Fatalf("test error: %v", err)
+	}
+
+	after, _ := parser.NewParser(scanner.NewScanner("print 1d / 3;").ScanTokens()).Parse()
+	if err := interpreter.Run(after); err != nil {
+		t.Fatalf("runtime error: %v", err)
+	}
+	if got := output.String(); got != "0.3333333333333333333333333333\n" {
+		t.Errorf("got %q, want the script's decimal precision back", got)
+	}
+	if _, err := interpreter.Environment().Get("m"); err == nil {
+		t.Error("still in the test's environment")
+	}
+}
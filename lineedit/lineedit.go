@@ -0,0 +1,246 @@
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInterrupted is returned by ReadLine when the user presses Ctrl-C.
+var ErrInterrupted = errors.New("interrupted")
+
+// CompleteFunc returns the candidates for the word ending at pos in line,
+// along with the index where that word starts.
+type CompleteFunc func(line string, pos int) (start int, candidates []string)
+
+// Editor is a minimal interactive line editor with history and tab completion.
+type Editor struct {
+	Prompt   string
+	Complete CompleteFunc
+
+	in      *bufio.Reader
+	out     io.Writer
+	fd      uintptr
+	raw     bool
+	history []string
+}
+
+func NewEditor(in *os.File, out io.Writer) *Editor {
+	return &Editor{
+		in:  bufio.NewReader(in),
+		out: out,
+		fd:  in.Fd(),
+		raw: isTerminal(in.Fd()),
+	}
+}
+
+// ReadLine reads one line of input; io.EOF is returned once the input is exhausted.
+func (e *Editor) ReadLine() (string, error) {
+	if !e.raw {
+		return e.readPlain()
+	}
+
+	restore, err := makeRaw(e.fd)
+	if err != nil {
+		return e.readPlain()
+	}
+	defer restore()
+
+	return e.edit()
+}
+
+// AddHistory records a line so that it can be recalled with the arrow keys.
+func (e *Editor) AddHistory(line string) {
+	if line == "" || (len(e.history) > 0 && e.history[len(e.history)-1] == line) {
+		return
+	}
+	e.history = append(e.history, line)
+}
+
+func (e *Editor) readPlain() (string, error) {
+	fmt.Fprint(e.out, e.Prompt)
+
+	line, err := e.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// the state of the line being edited
+type state struct {
+	buf       []rune
+	pos       int
+	histIndex int
+	lastTab   bool
+}
+
+func (e *Editor) edit() (string, error) {
+	st := &state{histIndex: len(e.history)}
+	e.refresh(st)
+
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		tab := false
+		switch r {
+		case '\r', '\n':
+			fmt.Fprint(e.out, "\r\n")
+			return string(st.buf), nil
+		case 3: // ctrl-c
+			fmt.Fprint(e.out, "^C\r\n")
+			return "", ErrInterrupted
+		case 4: // ctrl-d
+			if len(st.buf) == 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return "", io.EOF
+			}
+			st.delete()
+		case 127, 8: // backspace
+			if st.pos > 0 {
+				st.pos--
+				st.delete()
+			}
+		case 1: // ctrl-a
+			st.pos = 0
+		case 5: // ctrl-e
+			st.pos = len(st.buf)
+		case 21: // ctrl-u
+			st.buf = st.buf[st.pos:]
+			st.pos = 0
+		case '\t':
+			tab = true
+			e.complete(st)
+		case 27: // escape sequence
+			e.escape(st)
+		default:
+			if r >= ' ' {
+				st.insert(string(r))
+			}
+		}
+
+		st.lastTab = tab
+		e.refresh(st)
+	}
+}
+
+func (e *Editor) escape(st *state) {
+	next, _, err := e.in.ReadRune()
+	if err != nil || (next != '[' && next != 'O') {
+		return
+	}
+
+	code, _, err := e.in.ReadRune()
+	if err != nil {
+		return
+	}
+
+	switch code {
+	case 'A':
+		e.recall(st, -1)
+	case 'B':
+		e.recall(st, 1)
+	case 'C':
+		if st.pos < len(st.buf) {
+			st.pos++
+		}
+	case 'D':
+		if st.pos > 0 {
+			st.pos--
+		}
+	case 'H':
+		st.pos = 0
+	case 'F':
+		st.pos = len(st.buf)
+	case '3':
+		// delete key sends ESC [ 3 ~
+		if tilde, _, _ := e.in.ReadRune(); tilde == '~' {
+			st.delete()
+		}
+	}
+}
+
+func (e *Editor) recall(st *state, direction int) {
+	index := st.histIndex + direction
+	if index < 0 || index > len(e.history) {
+		return
+	}
+
+	st.histIndex = index
+	if index == len(e.history) {
+		st.buf = nil
+	} else {
+		st.buf = []rune(e.history[index])
+	}
+	st.pos = len(st.buf)
+}
+
+func (e *Editor) complete(st *state) {
+	if e.Complete == nil {
+		return
+	}
+
+	line := string(st.buf)
+	bytePos := len(string(st.buf[:st.pos]))
+	start, candidates := e.Complete(line, bytePos)
+	word := line[start:bytePos]
+
+	switch len(candidates) {
+	case 0:
+		fmt.Fprint(e.out, "\a")
+	case 1:
+		st.insert(strings.TrimPrefix(candidates[0], word))
+	default:
+		prefix := commonPrefix(candidates)
+		if len(prefix) > len(word) {
+			st.insert(strings.TrimPrefix(prefix, word))
+			return
+		}
+
+		// a second tab without progress lists every candidate
+		if st.lastTab {
+			fmt.Fprintf(e.out, "\r\n%s\r\n", strings.Join(candidates, "  "))
+		} else {
+			fmt.Fprint(e.out, "\a")
+		}
+	}
+}
+
+func (e *Editor) refresh(st *state) {
+	fmt.Fprintf(e.out, "\r%s%s\x1b[K", e.Prompt, string(st.buf))
+
+	if back := len(st.buf) - st.pos; back > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dD", back)
+	}
+}
+
+func (st *state) insert(text string) {
+	runes := []rune(text)
+	st.buf = append(st.buf[:st.pos], append(runes, st.buf[st.pos:]...)...)
+	st.pos += len(runes)
+}
+
+func (st *state) delete() {
+	if st.pos < len(st.buf) {
+		st.buf = append(st.buf[:st.pos], st.buf[st.pos+1:]...)
+	}
+}
+
+// commonPrefix shortens the prefix a rune at a time, so that it never ends in the middle of one.
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, word := range words[1:] {
+		for !strings.HasPrefix(word, prefix) {
+			_, size := utf8.DecodeLastRuneInString(prefix)
+			prefix = prefix[:len(prefix)-size]
+		}
+	}
+	return prefix
+}
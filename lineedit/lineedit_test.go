@@ -0,0 +1,74 @@
+package lineedit
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+)
+
+func newTestEditor(input string, complete CompleteFunc) *Editor {
+	return &Editor{
+		in:       bufio.NewReader(strings.NewReader(input)),
+		out:      io.Discard,
+		Complete: complete,
+	}
+}
+
+func wordCompleter(words ...string) CompleteFunc {
+	return func(line string, pos int) (int, []string) {
+		start := strings.LastIndex(line[:pos], " ") + 1
+		var candidates []string
+		for _, word := range words {
+			if strings.HasPrefix(word, line[start:pos]) {
+				candidates = append(candidates, word)
+			}
+		}
+		return start, candidates
+	}
+}
+
+func TestEditor(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"plain line", "print 1;\r", "print 1;"},
+		{"backspace", "prinx\x7ft 1;\r", "print 1;"},
+		{"cursor movement", "print 1;\x1b[D\x1b[D2\r", "print 21;"},
+		{"single completion", "pri\t 1;\r", "print 1;"},
+		{"common prefix", "var\t\r", "varia"},
+		{"ambiguous listing", "va\t\t\r", "va"},
+		{"no candidates", "zz\t\r", "zz"},
+		{"unicode completion", "caf\t\r", "café"},
+		{"prefix of whole runes", "r\t\r", "r"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			editor := newTestEditor(tt.input, wordCompleter("print", "variable", "variant", "value", "café", "rèle", "réel"))
+			got, err := editor.edit()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHistory(t *testing.T) {
+	editor := newTestEditor("\x1b[A\x1b[A\r", nil)
+	editor.AddHistory("var a = 1;")
+	editor.AddHistory("print a;")
+
+	got, err := editor.edit()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "var a = 1;" {
+		t.Errorf("got %q, want %q", got, "var a = 1;")
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package lineedit
+
+import "errors"
+
+// raw mode is only implemented for linux; everywhere else the editor falls back to plain line reading.
+func isTerminal(fd uintptr) bool { return false }
+
+func makeRaw(fd uintptr) (func(), error) {
+	return nil, errors.New("raw mode not supported on this platform")
+}
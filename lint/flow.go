@@ -0,0 +1,299 @@
+package lint
+
+import (
+	"golox/errors"
+	"golox/expr"
+	"golox/number"
+	"golox/stmt"
+	"golox/token"
+)
+
+// flowChecker walks every statement of the program, recording control flow findings.
+type flowChecker struct {
+	constants   []errors.Diagnostic
+	loops       []errors.Diagnostic
+	unreachable []errors.Diagnostic
+}
+
+// checkFlow runs the control flow analysis once for all the rules built on it.
+func checkFlow(f *file) *flowChecker {
+	if f.flow == nil {
+		f.flow = &flowChecker{}
+		f.flow.list(f.statements)
+	}
+	return f.flow
+}
+
+func checkConstantCondition(f *file) []errors.Diagnostic {
+	return checkFlow(f).constants
+}
+
+func checkInfiniteLoop(f *file) []errors.Diagnostic {
+	return checkFlow(f).loops
+}
+
+func checkUnreachable(f *file) []errors.Diagnostic {
+	return checkFlow(f).unreachable
+}
+
+func spanWarning(first, last int, where, message string) errors.Diagnostic {
+	return errors.Diagnostic{
+		Severity: errors.SeverityWarning,
+		Span:     errors.Span{Line: first, EndLine: last},
+		Where:    where,
+		Message:  message,
+	}
+}
+
+// list checks a sequence of statements, everything following a statement that never completes is unreachable.
+func (c *flowChecker) list(statements []stmt.Statement[any]) {
+	for i, st := range statements {
+		c.stmt(st)
+
+		if !completes(st) && i+1 < len(statements) {
+			first, _ := stmt.Lines(statements[i+1])
+			_, last := stmt.Lines(statements[len(statements)-1])
+			c.unreachable = append(c.unreachable, spanWarning(first, last, "", "Unreachable code."))
+
+			// the unreachable statements are still checked for their own problems
+			for _, rest := range statements[i+1:] {
+				c.stmt(rest)
+			}
+			return
+		}
+	}
+}
+
+func (c *flowChecker) stmt(st stmt.Statement[any]) {
+	if st != nil {
+		st.Accept(c)
+	}
+}
+
+func (c *flowChecker) VisitExpressionStmt(es *stmt.ExpressionStmt[any]) {}
+
+func (c *flowChecker) VisitPrintStmt(ps *stmt.PrintStmt[any]) {}
+
+func (c *flowChecker) VisitVarStmt(v *stmt.VarStmt[any]) {}
+
+func (c *flowChecker) VisitBlockStmt(b *stmt.BlockStmt[any]) {
+	c.list(b.Stmts)
+}
+
+func (c *flowChecker) VisitIfStmt(i *stmt.IfStmt[any]) {
+	if value, ok := constant(i.Condition); ok {
+		message := "Condition is always false, the then branch never runs."
+		if isTruthy(value) {
+			message = "Condition is always true."
+			if i.ElseBranch != nil {
+				message = "Condition is always true, the else branch never runs."
+			}
+		}
+		c.constants = append(c.constants, warning(i.Keyword, "%s", message))
+	}
+
+	c.stmt(i.ThenBranch)
+	c.stmt(i.ElseBranch)
+}
+
+func (c *flowChecker) VisitWhileStmt(w *stmt.WhileStmt[any]) {
+	first, last := stmt.Lines(w)
+
+	if value, ok := constant(w.Condition); ok {
+		switch {
+		// for loops without a condition get an injected true literal
+		case w.Keyword.TokenType == token.FOR && isTruthy(value):
+			c.loops = append(c.loops, spanWarning(first, last, w.Keyword.Lexeme, "Loop has no condition and never exits."))
+		case isTruthy(value):
+			c.constants = append(c.constants, warning(w.Keyword, "Condition is always true, the loop never exits."))
+		default:
+			c.constants = append(c.constants, warning(w.Keyword, "Condition is always false, the loop body never runs."))
+		}
+	}
+
+	c.stmt(w.Body)
+}
+
+func (c *flowChecker) VisitTestStmt(t *stmt.TestStmt[any]) {
+	c.stmt(t.Body)
+}
+
+func (c *flowChecker) VisitBenchStmt(b *stmt.BenchStmt[any]) {
+	c.stmt(b.Body)
+}
+
+func (c *flowChecker) VisitAssertStmt(a *stmt.AssertStmt[any]) {}
+
+// completes reports whether control can flow past st, the only statements that
+// can't are loops whose condition is always true since nothing can leave them.
+func completes(st stmt.Statement[any]) bool {
+	switch s := st.(type) {
+	case *stmt.WhileStmt[any]:
+		value, ok := constant(s.Condition)
+		return !ok || !isTruthy(value)
+
+	case *stmt.BlockStmt[any]:
+		for _, child := range s.Stmts {
+			if !completes(child) {
+				return false
+			}
+		}
+
+	case *stmt.IfStmt[any]:
+		if value, ok := constant(s.Condition); ok {
+			if isTruthy(value) {
+				return completes(s.ThenBranch)
+			}
+			return s.ElseBranch == nil || completes(s.ElseBranch)
+		}
+		return s.ElseBranch == nil || completes(s.ThenBranch) || completes(s.ElseBranch)
+	}
+	return true
+}
+
+func isTruthy(value any) bool {
+	if value == nil {
+		return false
+	}
+	if b, ok := value.(bool); ok {
+		return b
+	}
+	return true
+}
+
+// constant folds expressions made of literals, following the interpreter semantics.
+func constant(e expr.Expression[any]) (any, bool) {
+	switch e := e.(type) {
+	case *expr.Literal[any]:
+		return e.Value, true
+
+	case *expr.Grouping[any]:
+		return constant(e.Expression)
+
+	case *expr.Unary[any]:
+		right, ok := constant(e.Right)
+		if !ok {
+			return nil, false
+		}
+		switch e.Operator.TokenType {
+		case token.BANG:
+			return !isTruthy(right), true
+		case token.MINUS:
+			if number.IsNumber(right) {
+				return number.Negate(right), true
+			}
+		case token.TILDE:
+			if number.IsInteger(right) {
+				return number.Not(right), true
+			}
+		}
+
+	case *expr.Logical[any]:
+		// the right operand only matters when the left one doesn't decide
+		left, ok := constant(e.Left)
+		if !ok {
+			return nil, false
+		}
+		if e.Operator.TokenType == token.QUESTION_QUESTION {
+			if left != nil {
+				return left, true
+			}
+		} else if (e.Operator.TokenType == token.OR) == isTruthy(left) {
+			return left, true
+		}
+		return constant(e.Right)
+
+	case *expr.Conditional[any]:
+		condition, ok := constant(e.Condition)
+		if !ok {
+			return nil, false
+		}
+		if isTruthy(condition) {
+			return constant(e.Then)
+		}
+		return constant(e.Else)
+
+	case *expr.Binary[any]:
+		left, lok := constant(e.Left)
+		right, rok := constant(e.Right)
+		if !lok || !rok {
+			return nil, false
+		}
+		return binaryConstant(e.Operator.TokenType, left, right)
+	}
+
+	return nil, false
+}
+
+// the operations that can fail, a division by zero or a decimal past its bounds isn't folded
+var fallible = map[token.TokenType]func(a, b any) (any, error){
+	token.STAR:        number.Multiply,
+	token.SLASH:       number.Divide,
+	token.PERCENT:     number.Modulo,
+	token.TILDE_SLASH: number.FloorDivide,
+}
+
+func binaryConstant(operator token.TokenType, left, right any) (any, bool) {
+	bothNumbers := number.IsNumber(left) && number.IsNumber(right)
+
+	switch operator {
+	case token.EQUAL_EQUAL:
+		if bothNumbers {
+			return number.Equal(left, right), true
+		}
+		return left == right, true
+	case token.BANG_EQUAL:
+		if bothNumbers {
+			return !number.Equal(left, right), true
+		}
+		return left != right, true
+	}
+
+	if l, ok := left.(string); ok {
+		if r, ok := right.(string); ok && operator == token.PLUS {
+			return l + r, true
+		}
+		return nil, false
+	}
+
+	if number.IsInteger(left) && number.IsInteger(right) {
+		switch operator {
+		case token.AMPERSAND:
+			return number.And(left, right), true
+		case token.PIPE:
+			return number.Or(left, right), true
+		case token.CARET:
+			return number.Xor(left, right), true
+		}
+	}
+
+	// powers and shifts aren't folded, their results can be too large to compute while linting
+	if !bothNumbers || !number.CanMix(left, right) {
+		return nil, false
+	}
+
+	switch operator {
+	case token.PLUS:
+		return number.Add(left, right), true
+	case token.MINUS:
+		return number.Subtract(left, right), true
+	case token.STAR, token.SLASH, token.PERCENT, token.TILDE_SLASH:
+		if result, err := fallible[operator](left, right); err == nil {
+			return result, true
+		}
+		return nil, false
+	}
+
+	result, ordered := number.Compare(left, right)
+	switch operator {
+	case token.GREATER:
+		return ordered && result > 0, true
+	case token.GREATER_EQUAL:
+		return ordered && result >= 0, true
+	case token.LESS:
+		return ordered && result < 0, true
+	case token.LESS_EQUAL:
+		return ordered && result <= 0, true
+	}
+	return nil, false
+}
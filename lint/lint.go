@@ -0,0 +1,228 @@
+package lint
+
+import (
+	goerrors "errors"
+	"fmt"
+	"golox/errors"
+	"golox/interpreter"
+	"golox/parser"
+	"golox/resolver"
+	"golox/scanner"
+	"golox/stmt"
+	"golox/token"
+	"io"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Rule is a single check run by the linter.
+type Rule struct {
+	Name        string
+	Description string
+	check       func(f *file) []errors.Diagnostic
+}
+
+// Rules lists every available check, all of them are enabled by default.
+var Rules = []Rule{
+	{
+		Name:        "unused-variable",
+		Description: "a variable is declared but its value is never read",
+		check:       checkUnused,
+	},
+	{
+		Name:        "shadowed-variable",
+		Description: "a declaration hides a variable of the same name from an enclosing scope",
+		check:       checkShadowed,
+	},
+	{
+		Name:        "undefined-variable",
+		Description: "a variable is used but declared nowhere",
+		check:       checkUndefined,
+	},
+	{
+		Name:        "constant-condition",
+		Description: "an if or while condition always has the same value",
+		check:       checkConstantCondition,
+	},
+	{
+		Name:        "infinite-loop",
+		Description: "a for loop has no condition and nothing to exit it",
+		check:       checkInfiniteLoop,
+	},
+	{
+		Name:        "unreachable-code",
+		Description: "statements follow one that never completes",
+		check:       checkUnreachable,
+	},
+}
+
+// Config selects the rules to run.
+type Config struct {
+	Disabled map[string]bool
+}
+
+// the program being linted
+type file struct {
+	statements []stmt.Statement[any]
+	resolution *resolver.Result
+	flow       *flowChecker
+}
+
+// Source lints a program and returns its warnings sorted by line.
+// Scan and parse errors are returned as an error, the program isn't linted then.
+func Source(source string, config Config) ([]errors.Diagnostic, error) {
+	s := scanner.NewScanner(source)
+	s.Reporter = errors.NewErrorReporter(io.Discard)
+	s.KeepComments = true
+	tokens := s.ScanTokens()
+	if s.HadError() {
+		var errs []error
+		for _, d := range s.Errors() {
+			errs = append(errs, goerrors.New(d.String()))
+		}
+		return nil, goerrors.Join(errs...)
+	}
+
+	var code []token.Token
+	for _, tok := range tokens {
+		if tok.TokenType != token.COMMENT {
+			code = append(code, tok)
+		}
+	}
+
+	statements, errs := parser.NewParser(code).Parse()
+	if errs != nil {
+		return nil, goerrors.Join(errs...)
+	}
+
+	f := &file{
+		statements: statements,
+		resolution: resolver.Resolve(statements, interpreter.Builtins()...),
+	}
+	suppressed := suppressions(tokens)
+
+	var diagnostics []errors.Diagnostic
+	for _, rule := range Rules {
+		if config.Disabled[rule.Name] {
+			continue
+		}
+
+		for _, d := range rule.check(f) {
+			d.Rule = rule.Name
+			if !suppressed.covers(d) {
+				diagnostics = append(diagnostics, d)
+			}
+		}
+	}
+
+	slices.SortStableFunc(diagnostics, func(a, b errors.Diagnostic) int {
+		return a.Span.Line - b.Span.Line
+	})
+	return diagnostics, nil
+}
+
+func warning(tok token.Token, format string, args ...any) errors.Diagnostic {
+	return errors.Diagnostic{
+		Severity: errors.SeverityWarning,
+		Span:     errors.Span{Line: tok.Line, Column: tok.Column, EndLine: tok.Line, EndColumn: tok.Column + utf8.RuneCountInString(tok.Lexeme)},
+		Where:    tok.Lexeme,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
+
+func checkUnused(f *file) []errors.Diagnostic {
+	var diagnostics []errors.Diagnostic
+	for _, decl := range f.resolution.Declarations {
+		if len(decl.Reads) == 0 {
+			diagnostics = append(diagnostics, warning(decl.Name, "Variable '%s' is declared but never read.", decl.Name.Lexeme))
+		}
+	}
+	return diagnostics
+}
+
+func checkShadowed(f *file) []errors.Diagnostic {
+	var diagnostics []errors.Diagnostic
+	for _, decl := range f.resolution.Declarations {
+		switch {
+		case decl.Shadows == nil:
+		case decl.Shadows.Builtin:
+			diagnostics = append(diagnostics, warning(decl.Name, "Variable '%s' shadows the builtin of the same name.", decl.Name.Lexeme))
+		default:
+			diagnostics = append(diagnostics, warning(decl.Name, "Variable '%s' shadows the declaration on line %d.", decl.Name.Lexeme, decl.Shadows.Name.Line))
+		}
+	}
+	return diagnostics
+}
+
+func checkUndefined(f *file) []errors.Diagnostic {
+	var diagnostics []errors.Diagnostic
+	for _, ref := range f.resolution.Undefined() {
+		diagnostics = append(diagnostics, warning(ref.Name, "Variable '%s' is never defined.", ref.Name.Lexeme))
+	}
+	return diagnostics
+}
+
+// suppressed rules by line, the empty rule name stands for every rule
+type suppressionSet struct {
+	lines map[int][]string
+	file  []string
+}
+
+func (s suppressionSet) covers(d errors.Diagnostic) bool {
+	matches := func(rules []string) bool {
+		return slices.Contains(rules, "") || slices.Contains(rules, d.Rule)
+	}
+	return matches(s.file) || matches(s.lines[d.Span.Line])
+}
+
+// suppressions collects the "lint:ignore rule, ..." and "lint:file-ignore rule, ..." comments.
+// A lint:ignore comment applies to its own line when it trails code, otherwise to the next line of code.
+func suppressions(tokens []token.Token) suppressionSet {
+	set := suppressionSet{lines: map[int][]string{}}
+
+	for i, tok := range tokens {
+		if tok.TokenType != token.COMMENT {
+			continue
+		}
+
+		text := strings.TrimPrefix(tok.Lexeme, "//")
+		text = strings.TrimSuffix(strings.TrimPrefix(text, "/*"), "*/")
+		fields := strings.Fields(strings.ReplaceAll(text, ",", " "))
+		if len(fields) == 0 {
+			continue
+		}
+
+		rules := fields[1:]
+		if len(rules) == 0 {
+			rules = []string{""}
+		}
+
+		switch fields[0] {
+		case "lint:file-ignore":
+			set.file = append(set.file, rules...)
+		case "lint:ignore":
+			line := targetLine(tokens, i)
+			set.lines[line] = append(set.lines[line], rules...)
+		}
+	}
+	return set
+}
+
+func targetLine(tokens []token.Token, comment int) int {
+	for i := comment - 1; i >= 0; i-- {
+		if tokens[i].TokenType != token.COMMENT {
+			if tokens[i].Line == tokens[comment].Line {
+				return tokens[i].Line
+			}
+			break
+		}
+	}
+
+	for _, tok := range tokens[comment+1:] {
+		if tok.TokenType != token.COMMENT {
+			return tok.Line
+		}
+	}
+	return tokens[comment].Line
+}
@@ -0,0 +1,177 @@
+package lint
+
+import (
+	"golox/errors"
+	"strings"
+	"testing"
+)
+
+func lintLines(t *testing.T, source string, config Config) []string {
+	t.Helper()
+
+	diagnostics, err := Source(source, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var lines []string
+	for _, d := range diagnostics {
+		lines = append(lines, d.String())
+	}
+	return lines
+}
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []string
+	}{
+		{
+			name:   "unused",
+			source: "var a = 1; var b = 2; b = 3; print a;",
+			want:   []string{"[line 1] Warning at 'b': Variable 'b' is declared but never read. [unused-variable]"},
+		},
+		{
+			name:   "shadowed",
+			source: "var a = 1;\n{\n  var a = 2;\n  print a;\n}\nprint a;",
+			want:   []string{"[line 3] Warning at 'a': Variable 'a' shadows the declaration on line 1. [shadowed-variable]"},
+		},
+		{
+			name:   "shadowed builtin",
+			source: "var clock = 1; print clock;",
+			want:   []string{"[line 1] Warning at 'clock': Variable 'clock' shadows the builtin of the same name. [shadowed-variable]"},
+		},
+		{
+			name:   "undefined",
+			source: "print a;\nb = 1;\nprint clock();",
+			want: []string{
+				"[line 1] Warning at 'a': Variable 'a' is never defined. [undefined-variable]",
+				"[line 2] Warning at 'b': Variable 'b' is never defined. [undefined-variable]",
+			},
+		},
+		{
+			name:   "globals are visible before their declaration",
+			source: "{ print late; }\nvar late = 1;",
+			want:   nil,
+		},
+		{
+			name:   "initializer sees the enclosing variable",
+			source: "var a = 1;\n{\n  var a = a + 1;\n  print a;\n}",
+			want:   []string{"[line 3] Warning at 'a': Variable 'a' shadows the declaration on line 1. [shadowed-variable]"},
+		},
+		{
+			name:   "suppressions",
+			source: "var a; // lint:ignore unused-variable\n// lint:ignore\nvar b;\nvar c;",
+			want:   []string{"[line 4] Warning at 'c': Variable 'c' is declared but never read. [unused-variable]"},
+		},
+		{
+			name:   "file suppression",
+			source: "/* lint:file-ignore unused-variable, undefined-variable */\nvar a; print b;",
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := lintLines(t, tt.source, Config{})
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestDisabledRules(t *testing.T) {
+	got := lintLines(t, "var a; print b;", Config{Disabled: map[string]bool{"unused-variable": true}})
+	if len(got) != 1 || !strings.HasSuffix(got[0], "[undefined-variable]") {
+		t.Errorf("expected only the undefined variable warning, got %v", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	if _, err := Source("var = 1;", Config{}); err == nil {
+		t.Error("expected a parse error")
+	}
+}
+
+func TestScanErrors(t *testing.T) {
+	errors.HadError = false
+	_, err := Source("print \"a;", Config{})
+	if err == nil || !strings.Contains(err.Error(), "Unterminated string") {
+		t.Errorf("got error %v, want the scan error", err)
+	}
+	if errors.HadError {
+		t.Error("the scan error was reported outside of the result")
+	}
+}
+
+func TestFlowRules(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []string
+	}{
+		{
+			name:   "constant if",
+			source: "if (1 > 2) print 1; else print 2;\nif (!nil and \"a\" == \"a\") print 3; else print 4;",
+			want: []string{
+				"[line 1] Warning at 'if': Condition is always false, the then branch never runs. [constant-condition]",
+				"[line 2] Warning at 'if': Condition is always true, the else branch never runs. [constant-condition]",
+			},
+		},
+		{
+			name:   "constant while",
+			source: "while (false) print 1;",
+			want:   []string{"[line 1] Warning at 'while': Condition is always false, the loop body never runs. [constant-condition]"},
+		},
+		{
+			name:   "short circuit makes the condition constant",
+			source: "var a = 1;\nwhile (false and a) print a;\nwhile (a and false) print a;",
+			want:   []string{"[line 2] Warning at 'while': Condition is always false, the loop body never runs. [constant-condition]"},
+		},
+		{
+			name:   "infinite for and unreachable code",
+			source: "for (var i = 0;; i = i + 1) {\n  print i;\n}\nprint \"done\";\nprint \"really\";",
+			want: []string{
+				"[line 1] Warning at 'for': Loop has no condition and never exits. [infinite-loop]",
+				"[line 4] Warning: Unreachable code. [unreachable-code]",
+			},
+		},
+		{
+			name:   "infinite while",
+			source: "{\n  while (true) print 1;\n  print 2;\n}",
+			want: []string{
+				"[line 2] Warning at 'while': Condition is always true, the loop never exits. [constant-condition]",
+				"[line 3] Warning: Unreachable code. [unreachable-code]",
+			},
+		},
+		{
+			name:   "variable conditions",
+			source: "var a = true;\nwhile (a) a = false;\nif (a) print a;",
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := lintLines(t, tt.source, Config{})
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestUnreachableSpan(t *testing.T) {
+	diagnostics, err := Source("for (;;) {}\nprint 1;\n\nprint 2;", Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, d := range diagnostics {
+		if d.Rule == "unreachable-code" && (d.Span.Line != 2 || d.Span.EndLine != 4) {
+			t.Errorf("got span %+v, want lines 2 to 4", d.Span)
+		}
+	}
+}
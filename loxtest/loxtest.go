@@ -0,0 +1,275 @@
+// Package loxtest runs Lox scripts annotated with the results they must produce,
+// following the layout of the Crafting Interpreters test suite:
+//
+//	print 1 + 2; // expect: 3
+//	print -"a";  // expect runtime error: Operand must be a number
+//	var = 1;     // Error at '=': Expected a variable name.
+//	// [line 5] Error at end: Expected ';' after value.
+//
+// The test blocks of a script are run after it and must all pass:
+//
+//	test "addition" {
+//	  assert 1 + 2 == 3;
+//	}
+package loxtest
+
+import (
+	"bytes"
+	"fmt"
+	"golox/errors"
+	"golox/interpreter"
+	"golox/parser"
+	"golox/scanner"
+	"golox/stmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// Expectation is an expected line of output or runtime error, with the line of its annotation.
+type Expectation struct {
+	Line int
+	Text string
+}
+
+// Expectations are the annotations of a script.
+type Expectations struct {
+	Output []Expectation
+	// CompileErrors are formatted like "[line 3] Error at 'x': message"
+	CompileErrors []string
+	RuntimeError  *Expectation
+}
+
+var (
+	expectOutput       = regexp.MustCompile(`// expect: ?(.*)`)
+	expectRuntimeError = regexp.MustCompile(`// expect runtime error: (.+)`)
+	expectError        = regexp.MustCompile(`// (Error.*)`)
+	expectLineError    = regexp.MustCompile(`// \[(?:java )?line (\d+)\] (Error.*)`)
+)
+
+// Expect collects the annotations of source.
+func Expect(source string) Expectations {
+	var e Expectations
+
+	for i, text := range strings.Split(source, "\n") {
+		line := i + 1
+
+		if match := expectOutput.FindStringSubmatch(text); match != nil {
+			e.Output = append(e.Output, Expectation{Line: line, Text: match[1]})
+		} else if match := expectRuntimeError.FindStringSubmatch(text); match != nil {
+			e.RuntimeError = &Expectation{Line: line, Text: match[1]}
+		} else if match := expectLineError.FindStringSubmatch(text); match != nil {
+			e.CompileErrors = append(e.CompileErrors, fmt.Sprintf("[line %s] %s", match[1], match[2]))
+		} else if match := expectError.FindStringSubmatch(text); match != nil {
+			e.CompileErrors = append(e.CompileErrors, fmt.Sprintf("[line %d] %s", line, match[1]))
+		}
+	}
+	return e
+}
+
+// Result is what running a script produced.
+type Result struct {
+	Output        []string
+	CompileErrors []string
+	RuntimeError  *Expectation
+	Tests         []TestResult
+}
+
+// TestResult is the outcome of a test block, Error is nil when it passed.
+type TestResult struct {
+	Name   string
+	Line   int
+	Output []string
+	Error  error
+}
+
+// Passed counts the test blocks that passed.
+func (r Result) Passed() int {
+	passed := 0
+	for _, test := range r.Tests {
+		if test.Error == nil {
+			passed++
+		}
+	}
+	return passed
+}
+
+// Run scans, parses and runs source in a fresh interpreter, capturing what it prints.
+// When hook isn't nil, it makes the interpreter hook for the parsed program.
+func Run(source string, hook func(statements []stmt.Statement[any]) interpreter.Hook) Result {
+	var r Result
+
+	s := scanner.NewScanner(source)
+	s.Reporter = errors.NewErrorReporter(io.Discard)
+	tokens := s.ScanTokens()
+	for _, d := range s.Errors() {
+		r.CompileErrors = append(r.CompileErrors, compileError(d))
+	}
+
+	statements, errs := parser.NewParser(tokens).Parse()
+	for _, err := range errs {
+		if d, ok := parser.Diagnostic(err); ok {
+			r.CompileErrors = append(r.CompileErrors, compileError(d))
+		}
+	}
+	if r.CompileErrors != nil {
+		return r
+	}
+
+	var output bytes.Buffer
+	interp := interpreter.NewInterpreter()
+	interp.Out = &output
+	if hook != nil {
+		interp.Hook = hook(statements)
+	}
+
+	err := interp.Run(statements)
+	if rerr, ok := err.(interpreter.RuntimeError); ok {
+		r.RuntimeError = &Expectation{Line: rerr.Token.Line, Text: rerr.Message}
+	}
+
+	r.Output = lines(output.String())
+
+	// the tests run after the script, each from a copy of the variables it left
+	scope := interp.TestScope()
+	for _, st := range statements {
+		test, ok := st.(*stmt.TestStmt[any])
+		if !ok {
+			continue
+		}
+
+		output.Reset()
+		err := interp.RunTest(test, scope)
+		r.Tests = append(r.Tests, TestResult{Name: test.Name, Line: test.Keyword.Line, Output: lines(output.String()), Error: err})
+	}
+	return r
+}
+
+func lines(output string) []string {
+	if text := strings.TrimSuffix(output, "\n"); text != "" {
+		return strings.Split(text, "\n")
+	}
+	return nil
+}
+
+func compileError(d errors.Diagnostic) string {
+	switch d.Where {
+	case "":
+		return fmt.Sprintf("[line %d] Error: %s", d.Span.Line, d.Message)
+	case "end":
+		return fmt.Sprintf("[line %d] Error at end: %s", d.Span.Line, d.Message)
+	}
+	return fmt.Sprintf("[line %d] Error at '%s': %s", d.Span.Line, d.Where, d.Message)
+}
+
+// Check compares a result to the expectations and describes every difference.
+func (e Expectations) Check(r Result) []string {
+	var failures []string
+	fail := func(format string, args ...any) {
+		failures = append(failures, fmt.Sprintf(format, args...))
+	}
+
+	for i := 0; i < max(len(e.CompileErrors), len(r.CompileErrors)); i++ {
+		switch {
+		case i >= len(r.CompileErrors):
+			fail("Missing expected error: %s", e.CompileErrors[i])
+		case i >= len(e.CompileErrors):
+			fail("Unexpected error: %s", r.CompileErrors[i])
+		case e.CompileErrors[i] != r.CompileErrors[i]:
+			fail("Expected error %q and got %q.", e.CompileErrors[i], r.CompileErrors[i])
+		}
+	}
+
+	switch {
+	case e.RuntimeError == nil && r.RuntimeError != nil:
+		fail("Unexpected runtime error on line %d: %s", r.RuntimeError.Line, r.RuntimeError.Text)
+	case e.RuntimeError != nil && r.RuntimeError == nil:
+		fail("Expected runtime error %q on line %d and got none.", e.RuntimeError.Text, e.RuntimeError.Line)
+	case e.RuntimeError != nil && *e.RuntimeError != *r.RuntimeError:
+		fail("Expected runtime error %q on line %d and got %q on line %d.",
+			e.RuntimeError.Text, e.RuntimeError.Line, r.RuntimeError.Text, r.RuntimeError.Line)
+	}
+
+	for i := 0; i < max(len(e.Output), len(r.Output)); i++ {
+		switch {
+		case i >= len(r.Output):
+			fail("Missing expected output %q on line %d.", e.Output[i].Text, e.Output[i].Line)
+		case i >= len(e.Output):
+			fail("Got output %q when none was expected.", r.Output[i])
+		case e.Output[i].Text != r.Output[i]:
+			fail("Expected output %q on line %d and got %q.", e.Output[i].Text, e.Output[i].Line, r.Output[i])
+		}
+	}
+
+	for _, test := range r.Tests {
+		if test.Error != nil {
+			fail("Test %q on line %d failed: %v", test.Name, test.Line, test.Error)
+			for _, line := range test.Output {
+				fail("    %s", line)
+			}
+		}
+	}
+
+	return failures
+}
+
+// Files returns the scripts named by paths, directories stand for every script below them.
+func Files(paths ...string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		err := filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			// a script named on its own is run whatever its extension
+			extension := filepath.Ext(file)
+			if !entry.IsDir() && (file == path || extension == ".lox" || extension == ".golox") {
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// RunFile runs a script as a Go test, failing t with the differences from its annotations.
+func RunFile(t testing.TB, path string) {
+	t.Helper()
+
+	source, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, failure := range Expect(string(source)).Check(Run(string(source), nil)) {
+		t.Error(failure)
+	}
+}
+
+// RunDir runs every script below dir as a subtest named after its path.
+func RunDir(t *testing.T, dir string) {
+	t.Helper()
+
+	files, err := Files(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatalf("no scripts in %s", dir)
+	}
+
+	for _, file := range files {
+		name, _ := filepath.Rel(dir, file)
+		t.Run(strings.TrimSuffix(name, filepath.Ext(name)), func(t *testing.T) {
+			RunFile(t, file)
+		})
+	}
+}
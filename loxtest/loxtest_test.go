@@ -0,0 +1,89 @@
+package loxtest
+
+import (
+	"slices"
+	"testing"
+)
+
+// TestSuite runs the scripts of the conformance suite.
+func TestSuite(t *testing.T) {
+	RunDir(t, "../test")
+}
+
+func TestExpect(t *testing.T) {
+	source := `print 1; // expect: 1
+print "";  // expect:
+-nil; // expect runtime error: Operand must be a number
+var = 1; // Error at '=': Expected a variable name.
+// [line 7] Error at end: Expected ';' after value.
+`
+	e := Expect(source)
+
+	if want := []Expectation{{Line: 1, Text: "1"}, {Line: 2, Text: ""}}; !slices.Equal(e.Output, want) {
+		t.Errorf("got output %v, want %v", e.Output, want)
+	}
+	if e.RuntimeError == nil || *e.RuntimeError != (Expectation{Line: 3, Text: "Operand must be a number"}) {
+		t.Errorf("got runtime error %v", e.RuntimeError)
+	}
+	want := []string{"[line 4] Error at '=': Expected a variable name.", "[line 7] Error at end: Expected ';' after value."}
+	if !slices.Equal(e.CompileErrors, want) {
+		t.Errorf("got errors %q, want %q", e.CompileErrors, want)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	e := Expect("print 1; // expect: 1\nprint 2; // expect: 3\n")
+	failures := e.Check(Run("print 1;\nprint 2;\nprint 4;\n", nil))
+
+	want := []string{
+		`Expected output "3" on line 2 and got "2".`,
+		`Got output "4" when none was expected.`,
+	}
+	if !slices.Equal(failures, want) {
+		t.Errorf("got failures %q, want %q", failures, want)
+	}
+}
+
+func TestRunTests(t *testing.T) {
+	r := Run(`var n = 2;
+test "passes" { assert n == 2; }
+test "fails" {
+  print "debug";
+  assert n * 2 == 5;
+}
+`, nil)
+
+	if len(r.Tests) != 2 || r.Passed() != 1 {
+		t.Fatalf("got tests %+v", r.Tests)
+	}
+	failed := r.Tests[1]
+	if failed.Name != "fails" || failed.Line != 3 || !slices.Equal(failed.Output, []string{"debug"}) {
+		t.Errorf("got failed test %+v", failed)
+	}
+	if want := "[line 5] RuntimeError: Assertion failed: n * 2 == 5 (got 4 == 5)"; failed.Error == nil || failed.Error.Error() != want {
+		t.Errorf("got error %v, want %q", failed.Error, want)
+	}
+}
+
+func TestTestsAreIsolated(t *testing.T) {
+	r := Run(`var n = 1;
+test "changes n" {
+  n = 5;
+  decimalPrecision(2);
+  assert n == 5;
+}
+test "sees the script's n" {
+  assert n == 1;
+  assert 1d / 3 == decimal("0.3333333333333333333333333333");
+}
+`, nil)
+
+	for _, test := range r.Tests {
+		if test.Error != nil {
+			t.Errorf("test %q failed: %v", test.Name, test.Error)
+		}
+	}
+	if len(r.Tests) != 2 {
+		t.Fatalf("got tests %+v", r.Tests)
+	}
+}
@@ -0,0 +1,346 @@
+package lsp
+
+import (
+	"fmt"
+	"golox/errors"
+	"golox/interpreter"
+	"golox/lint"
+	"golox/parser"
+	"golox/resolver"
+	"golox/scanner"
+	"golox/token"
+	"io"
+	"slices"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// document is an open file together with everything derived from its text.
+//
+// Positions are counted in runes like the columns of the tokens, they are
+// converted to the encoding of the client by encode and decode.
+type document struct {
+	uri         string
+	text        string
+	lines       []string
+	utf16       bool
+	tokens      []token.Token // comments included
+	code        []token.Token
+	resolution  *resolver.Result
+	diagnostics []Diagnostic
+}
+
+func analyze(uri, text string, utf16Positions bool) *document {
+	d := &document{uri: uri, text: text, lines: strings.Split(text, "\n"), utf16: utf16Positions, diagnostics: []Diagnostic{}}
+
+	// the scan errors are published as diagnostics, not written to the server's stderr
+	s := scanner.NewScanner(text)
+	s.Reporter = errors.NewErrorReporter(io.Discard)
+	s.KeepComments = true
+	d.tokens = s.ScanTokens()
+	for _, tok := range d.tokens {
+		if tok.TokenType != token.COMMENT {
+			d.code = append(d.code, tok)
+		}
+	}
+
+	for _, diagnostic := range s.Errors() {
+		d.diagnostics = append(d.diagnostics, d.convertDiagnostic(diagnostic))
+	}
+
+	// the statements parsed before an error are still resolved
+	statements, errs := parser.NewParser(d.code).Parse()
+	for _, err := range errs {
+		if diagnostic, ok := parser.Diagnostic(err); ok {
+			d.diagnostics = append(d.diagnostics, d.convertDiagnostic(diagnostic))
+		}
+	}
+	d.resolution = resolver.Resolve(statements, interpreter.Builtins()...)
+
+	if len(d.diagnostics) == 0 {
+		warnings, err := lint.Source(text, lint.Config{})
+		if err == nil {
+			for _, diagnostic := range warnings {
+				d.diagnostics = append(d.diagnostics, d.convertDiagnostic(diagnostic))
+			}
+		}
+	}
+
+	return d
+}
+
+func (d *document) convertDiagnostic(diagnostic errors.Diagnostic) Diagnostic {
+	span := diagnostic.Span
+	r := Range{Start: Position{Line: span.Line - 1}, End: Position{Line: span.EndLine}}
+	if span.Column > 0 {
+		r = Range{
+			Start: Position{Line: span.Line - 1, Character: span.Column - 1},
+			End:   Position{Line: span.EndLine - 1, Character: span.EndColumn - 1},
+		}
+	}
+
+	severity := severityError
+	if diagnostic.Severity == errors.SeverityWarning {
+		severity = severityWarning
+	}
+
+	return Diagnostic{
+		Range:    d.encodeRange(r),
+		Severity: severity,
+		Code:     diagnostic.Rule,
+		Source:   "golox",
+		Message:  diagnostic.Message,
+	}
+}
+
+// tokenRange returns the range of a single line token.
+func tokenRange(tok token.Token) Range {
+	start := Position{Line: tok.Line - 1, Character: tok.Column - 1}
+	return Range{Start: start, End: Position{Line: start.Line, Character: start.Character + utf8.RuneCountInString(tok.Lexeme)}}
+}
+
+// encode converts a position counted in runes to the encoding of the client,
+// UTF-16 code units unless it accepted UTF-32. Characters past the end of the line
+// are left as they are.
+func (d *document) encode(p Position) Position {
+	if !d.utf16 || p.Line < 0 || p.Line >= len(d.lines) {
+		return p
+	}
+
+	runes, units := 0, 0
+	for _, char := range d.lines[p.Line] {
+		if runes == p.Character {
+			break
+		}
+		runes++
+		units += utf16.RuneLen(char)
+	}
+	p.Character = units + p.Character - runes
+	return p
+}
+
+func (d *document) encodeRange(r Range) Range {
+	return Range{Start: d.encode(r.Start), End: d.encode(r.End)}
+}
+
+// decode converts a position from the client to one counted in runes, a position
+// in the middle of a surrogate pair moves to the end of its character.
+func (d *document) decode(p Position) Position {
+	if !d.utf16 || p.Line < 0 || p.Line >= len(d.lines) {
+		return p
+	}
+
+	runes, units := 0, 0
+	for _, char := range d.lines[p.Line] {
+		if units >= p.Character {
+			break
+		}
+		runes++
+		units += utf16.RuneLen(char)
+	}
+	p.Character = runes + max(p.Character-units, 0)
+	return p
+}
+
+// declarationAt returns the declaration of the variable named at pos.
+func (d *document) declarationAt(pos Position) (*resolver.Declaration, Range, bool) {
+	for _, decl := range d.resolution.Declarations {
+		if r := tokenRange(decl.Name); r.contains(pos) {
+			return decl, r, true
+		}
+	}
+
+	for _, ref := range d.resolution.References {
+		if r := tokenRange(ref.Name); r.contains(pos) && ref.Declaration != nil {
+			return ref.Declaration, r, true
+		}
+	}
+
+	return nil, Range{}, false
+}
+
+func (d *document) definition(pos Position) *Location {
+	decl, _, ok := d.declarationAt(pos)
+	if !ok || decl.Builtin {
+		return nil
+	}
+	return &Location{URI: d.uri, Range: d.encodeRange(tokenRange(decl.Name))}
+}
+
+func (d *document) references(pos Position, includeDeclaration bool) []Location {
+	locations := []Location{}
+
+	decl, _, ok := d.declarationAt(pos)
+	if !ok {
+		return locations
+	}
+
+	if includeDeclaration && !decl.Builtin {
+		locations = append(locations, Location{URI: d.uri, Range: d.encodeRange(tokenRange(decl.Name))})
+	}
+	for _, ref := range d.resolution.References {
+		if ref.Declaration == decl {
+			locations = append(locations, Location{URI: d.uri, Range: d.encodeRange(tokenRange(ref.Name))})
+		}
+	}
+	return locations
+}
+
+func (d *document) hover(pos Position) *Hover {
+	decl, r, ok := d.declarationAt(pos)
+	if !ok {
+		return nil
+	}
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "```lox\nvar %s\n```\n", decl.Name.Lexeme)
+
+	switch {
+	case decl.Builtin:
+		builder.WriteString("Builtin.")
+	case decl.Depth == 0:
+		fmt.Fprintf(&builder, "Global variable declared on line %d.", decl.Name.Line)
+	default:
+		fmt.Fprintf(&builder, "Local variable declared on line %d, %d scope(s) deep.", decl.Name.Line, decl.Depth)
+	}
+
+	if decl.Shadows != nil && !decl.Shadows.Builtin {
+		fmt.Fprintf(&builder, " Shadows the variable declared on line %d.", decl.Shadows.Name.Line)
+	}
+	fmt.Fprintf(&builder, "\n\nRead %d time(s), assigned %d time(s).", len(decl.Reads), len(decl.Writes))
+
+	return &Hover{Contents: MarkupContent{Kind: "markdown", Value: builder.String()}, Range: d.encodeRange(r)}
+}
+
+func (d *document) symbols() []DocumentSymbol {
+	symbols := []DocumentSymbol{}
+	for _, decl := range d.resolution.Declarations {
+		detail := "global"
+		if decl.Depth > 0 {
+			detail = "local"
+		}
+
+		r := d.encodeRange(tokenRange(decl.Name))
+		symbols = append(symbols, DocumentSymbol{
+			Name:           decl.Name.Lexeme,
+			Detail:         detail,
+			Kind:           symbolKindVariable,
+			Range:          r,
+			SelectionRange: r,
+		})
+	}
+	return symbols
+}
+
+// completion offers the keywords and the variables visible at pos.
+//
+// Scopes are tracked on the tokens rather than the syntax tree so that completion
+// keeps working while the code being typed doesn't parse.
+func (d *document) completion(pos Position) []CompletionItem {
+	scopes := [][]string{{}}
+	for i, tok := range d.code {
+		if tok.TokenType == token.EOF || !tokenRange(tok).Start.before(pos) {
+			break
+		}
+
+		switch tok.TokenType {
+		case token.LEFT_BRACE:
+			scopes = append(scopes, nil)
+		case token.RIGHT_BRACE:
+			if len(scopes) > 1 {
+				scopes = scopes[:len(scopes)-1]
+			}
+		case token.IDENTIFIER:
+			if i > 0 && d.code[i-1].TokenType == token.VAR {
+				scopes[len(scopes)-1] = append(scopes[len(scopes)-1], tok.Lexeme)
+			}
+		}
+	}
+
+	// script level variables are visible everywhere
+	names := slices.Concat(scopes...)
+	for _, decl := range d.resolution.Declarations {
+		if decl.Depth <= 0 {
+			names = append(names, decl.Name.Lexeme)
+		}
+	}
+	names = append(names, interpreter.Builtins()...)
+	slices.Sort(names)
+	names = slices.Compact(names)
+
+	items := []CompletionItem{}
+	for _, keyword := range scanner.Keywords() {
+		items = append(items, CompletionItem{Label: keyword, Kind: completionKindKeyword})
+	}
+	for _, name := range names {
+		items = append(items, CompletionItem{Label: name, Kind: completionKindVariable})
+	}
+	return items
+}
+
+// semantic token types, the index in this legend is what gets encoded
+var tokenLegend = []string{"keyword", "variable", "string", "number", "comment", "operator"}
+
+func semanticType(tokenType token.TokenType) (int, bool) {
+	switch {
+	case tokenType >= token.AND && tokenType <= token.WHILE:
+		return 0, true
+	case tokenType == token.IDENTIFIER:
+		return 1, true
+	case tokenType == token.STRING, tokenType == token.INTERPOLATION:
+		return 2, true
+	case tokenType == token.NUMBER:
+		return 3, true
+	case tokenType == token.COMMENT:
+		return 4, true
+	}
+
+	switch tokenType {
+	case token.MINUS, token.PLUS, token.SLASH, token.STAR, token.BANG, token.BANG_EQUAL,
+		token.EQUAL, token.EQUAL_EQUAL, token.GREATER, token.GREATER_EQUAL, token.LESS, token.LESS_EQUAL,
+		token.PERCENT, token.STAR_STAR, token.TILDE_SLASH, token.AMPERSAND, token.PIPE, token.CARET,
+		token.TILDE, token.LESS_LESS, token.GREATER_GREATER, token.PLUS_EQUAL, token.MINUS_EQUAL,
+		token.STAR_EQUAL, token.SLASH_EQUAL, token.PERCENT_EQUAL, token.PLUS_PLUS, token.MINUS_MINUS,
+		token.QUESTION, token.COLON, token.QUESTION_QUESTION:
+		return 5, true
+	}
+	return 0, false
+}
+
+// semanticTokens encodes the tokens as relative (line, start, length, type, modifiers) tuples,
+// tokens spanning several lines are split at line breaks.
+func (d *document) semanticTokens() SemanticTokens {
+	data := []int{}
+	previous := Position{}
+
+	for _, tok := range d.tokens {
+		tokenType, ok := semanticType(tok.TokenType)
+		if !ok {
+			continue
+		}
+
+		segments := strings.Split(tok.Lexeme, "\n")
+		line := tok.Line - (len(segments) - 1) - 1
+		for i, segment := range segments {
+			start := Position{Line: line + i}
+			if i == 0 {
+				start.Character = tok.Column - 1
+			}
+			if segment == "" {
+				continue
+			}
+			end := d.encode(Position{Line: start.Line, Character: start.Character + utf8.RuneCountInString(segment)})
+			start = d.encode(start)
+
+			deltaStart := start.Character
+			if start.Line == previous.Line {
+				deltaStart -= previous.Character
+			}
+			data = append(data, start.Line-previous.Line, deltaStart, end.Character-start.Character, tokenType, 0)
+			previous = start
+		}
+	}
+
+	return SemanticTokens{Data: data}
+}
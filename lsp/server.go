@@ -0,0 +1,218 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"golox/wire"
+	"io"
+	"slices"
+)
+
+// Server is a language server speaking LSP over a pair of streams, usually stdin and stdout.
+type Server struct {
+	in   *bufio.Reader
+	out  io.Writer
+	docs map[string]*document
+	// whether positions count UTF-16 code units, the LSP default, rather than runes
+	utf16 bool
+	// the first failed write, the connection is unusable after it
+	err error
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:    bufio.NewReader(in),
+		out:   out,
+		docs:  map[string]*document{},
+		utf16: true,
+	}
+}
+
+// Run serves requests until the client sends "exit" or closes the input.
+func (s *Server) Run() error {
+	for {
+		body, err := wire.Read(s.in)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var msg message
+		if err := json.Unmarshal(body, &msg); err != nil {
+			return fmt.Errorf("invalid message: %v", err)
+		}
+
+		if msg.Method == "exit" {
+			return nil
+		}
+		s.handle(msg)
+		if s.err != nil {
+			return s.err
+		}
+	}
+}
+
+func (s *Server) handle(msg message) {
+	result, rerr := s.dispatch(msg)
+
+	// notifications get no response
+	switch {
+	case msg.ID == nil:
+	case rerr != nil:
+		s.write(errorResponse{JSONRPC: "2.0", ID: msg.ID, Error: *rerr})
+	default:
+		s.write(response{JSONRPC: "2.0", ID: msg.ID, Result: result})
+	}
+}
+
+func (s *Server) write(value any) {
+	if s.err == nil {
+		s.err = wire.Write(s.out, value)
+	}
+}
+
+func (s *Server) dispatch(msg message) (any, *responseError) {
+	switch msg.Method {
+	case "initialize":
+		var params InitializeParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		return s.initialize(params), nil
+	case "initialized", "$/cancelRequest", "$/setTrace":
+		return nil, nil
+	case "shutdown":
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var params DidOpenParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		s.update(params.TextDocument.URI, params.TextDocument.Text)
+		return nil, nil
+
+	case "textDocument/didChange":
+		var params DidChangeParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		// the server asks for full synchronization, the last change holds the whole text
+		if n := len(params.ContentChanges); n > 0 {
+			s.update(params.TextDocument.URI, params.ContentChanges[n-1].Text)
+		}
+		return nil, nil
+
+	case "textDocument/didClose":
+		var params DidCloseParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		delete(s.docs, params.TextDocument.URI)
+		s.publish(PublishDiagnosticsParams{URI: params.TextDocument.URI, Diagnostics: []Diagnostic{}})
+		return nil, nil
+
+	case "textDocument/definition":
+		return withPosition(s, msg, func(d *document, pos Position) any { return d.definition(pos) })
+	case "textDocument/hover":
+		return withPosition(s, msg, func(d *document, pos Position) any { return d.hover(pos) })
+	case "textDocument/completion":
+		return withPosition(s, msg, func(d *document, pos Position) any { return d.completion(pos) })
+
+	case "textDocument/references":
+		var params ReferenceParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		d, rerr := s.document(params.TextDocument.URI)
+		if rerr != nil {
+			return nil, rerr
+		}
+		return d.references(d.decode(params.Position), params.Context.IncludeDeclaration), nil
+
+	case "textDocument/documentSymbol":
+		return withDocument(s, msg, func(d *document) any { return d.symbols() })
+	case "textDocument/semanticTokens/full":
+		return withDocument(s, msg, func(d *document) any { return d.semanticTokens() })
+	}
+
+	return nil, &responseError{Code: codeMethodNotFound, Message: fmt.Sprintf("method not found: %s", msg.Method)}
+}
+
+// initialize negotiates UTF-32 positions when the client offers them, they are
+// the rune columns of the scanner and need no conversion.
+func (s *Server) initialize(params InitializeParams) any {
+	encoding := "utf-16"
+	if slices.Contains(params.Capabilities.General.PositionEncodings, "utf-32") {
+		encoding, s.utf16 = "utf-32", false
+	}
+
+	return map[string]any{
+		"capabilities": map[string]any{
+			"positionEncoding":       encoding,
+			"textDocumentSync":       1, // full document on every change
+			"definitionProvider":     true,
+			"referencesProvider":     true,
+			"hoverProvider":          true,
+			"documentSymbolProvider": true,
+			"completionProvider":     map[string]any{},
+			"semanticTokensProvider": map[string]any{
+				"legend": map[string]any{"tokenTypes": tokenLegend, "tokenModifiers": []string{}},
+				"full":   true,
+			},
+		},
+		"serverInfo": map[string]any{"name": "golox"},
+	}
+}
+
+// update reanalyzes a document and publishes its diagnostics.
+func (s *Server) update(uri, text string) {
+	d := analyze(uri, text, s.utf16)
+	s.docs[uri] = d
+	s.publish(PublishDiagnosticsParams{URI: uri, Diagnostics: d.diagnostics})
+}
+
+func (s *Server) publish(params PublishDiagnosticsParams) {
+	s.write(notification{JSONRPC: "2.0", Method: "textDocument/publishDiagnostics", Params: params})
+}
+
+func (s *Server) document(uri string) (*document, *responseError) {
+	d, ok := s.docs[uri]
+	if !ok {
+		return nil, &responseError{Code: codeInvalidParams, Message: fmt.Sprintf("unknown document: %s", uri)}
+	}
+	return d, nil
+}
+
+func withDocument(s *Server, msg message, fn func(d *document) any) (any, *responseError) {
+	var params DocumentParams
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		return nil, invalidParams(err)
+	}
+
+	d, rerr := s.document(params.TextDocument.URI)
+	if rerr != nil {
+		return nil, rerr
+	}
+	return fn(d), nil
+}
+
+func withPosition(s *Server, msg message, fn func(d *document, pos Position) any) (any, *responseError) {
+	var params TextDocumentPositionParams
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		return nil, invalidParams(err)
+	}
+
+	d, rerr := s.document(params.TextDocument.URI)
+	if rerr != nil {
+		return nil, rerr
+	}
+	return fn(d, d.decode(params.Position)), nil
+}
+
+func invalidParams(err error) *responseError {
+	return &responseError{Code: codeInvalidParams, Message: err.Error()}
+}
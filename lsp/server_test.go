@@ -0,0 +1,299 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"golox/errors"
+	"golox/wire"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// client drives a server over pipes the way an editor would.
+type client struct {
+	t      *testing.T
+	in     io.WriteCloser
+	out    *bufio.Reader
+	nextID int
+	done   chan error
+}
+
+func newClient(t *testing.T) *client {
+	t.Helper()
+
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	c := &client{t: t, in: clientOut, out: bufio.NewReader(clientIn), done: make(chan error, 1)}
+
+	go func() {
+		c.done <- NewServer(serverIn, serverOut).Run()
+		serverOut.Close()
+	}()
+	t.Cleanup(func() { clientOut.Close() })
+	return c
+}
+
+func (c *client) send(value any) {
+	c.t.Helper()
+	if err := wire.Write(c.in, value); err != nil {
+		c.t.Fatalf("write: %v", err)
+	}
+}
+
+// receive reads the next message from the server.
+func (c *client) receive() map[string]any {
+	c.t.Helper()
+	body, err := wire.Read(c.out)
+	if err != nil {
+		c.t.Fatalf("read: %v", err)
+	}
+
+	var msg map[string]any
+	if err := json.Unmarshal(body, &msg); err != nil {
+		c.t.Fatalf("decode: %v", err)
+	}
+	return msg
+}
+
+func (c *client) notify(method string, params any) {
+	c.t.Helper()
+	c.send(map[string]any{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+func (c *client) request(method string, params any) map[string]any {
+	c.t.Helper()
+	c.nextID++
+	c.send(map[string]any{"jsonrpc": "2.0", "id": c.nextID, "method": method, "params": params})
+
+	msg := c.receive()
+	if id, _ := msg["id"].(float64); int(id) != c.nextID {
+		c.t.Fatalf("got response %v, want id %d", msg, c.nextID)
+	}
+	return msg
+}
+
+// roundTrip converts a result to JSON and back into a typed value.
+func roundTrip[T any](t *testing.T, value any) T {
+	t.Helper()
+	var result T
+	data, _ := json.Marshal(value)
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("decode %s: %v", data, err)
+	}
+	return result
+}
+
+const uri = "file:///test.lox"
+
+func (c *client) open(text string) PublishDiagnosticsParams {
+	c.t.Helper()
+	c.notify("textDocument/didOpen", map[string]any{
+		"textDocument": map[string]any{"uri": uri, "languageId": "lox", "version": 1, "text": text},
+	})
+
+	msg := c.receive()
+	if msg["method"] != "textDocument/publishDiagnostics" {
+		c.t.Fatalf("got %v, want diagnostics", msg)
+	}
+	return roundTrip[PublishDiagnosticsParams](c.t, msg["params"])
+}
+
+func position(line, character int) map[string]any {
+	return map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+		"position":     map[string]any{"line": line, "character": character},
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	c := newClient(t)
+
+	init := c.request("initialize", map[string]any{"capabilities": map[string]any{}})
+	capabilities := init["result"].(map[string]any)["capabilities"].(map[string]any)
+	for _, provider := range []string{"definitionProvider", "referencesProvider", "hoverProvider", "documentSymbolProvider"} {
+		if capabilities[provider] != true {
+			t.Errorf("%s not advertised", provider)
+		}
+	}
+	c.notify("initialized", map[string]any{})
+
+	unknown := c.request("textDocument/unknown", map[string]any{})
+	if code := unknown["error"].(map[string]any)["code"]; code != float64(codeMethodNotFound) {
+		t.Errorf("got error code %v, want %d", code, codeMethodNotFound)
+	}
+
+	if shutdown := c.request("shutdown", nil); shutdown["result"] != nil {
+		t.Errorf("got shutdown result %v, want null", shutdown["result"])
+	}
+	c.notify("exit", nil)
+
+	if err := <-c.done; err != nil {
+		t.Errorf("server failed: %v", err)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	c := newClient(t)
+
+	published := c.open("var a = 1;\nprint a\n")
+	if len(published.Diagnostics) != 1 {
+		t.Fatalf("got %v, want one parse error", published.Diagnostics)
+	}
+	d := published.Diagnostics[0]
+	if d.Severity != severityError || d.Range.Start.Line != 2 || !strings.Contains(d.Message, "Expected ';'") {
+		t.Errorf("got %+v", d)
+	}
+
+	c.notify("textDocument/didChange", map[string]any{
+		"textDocument":   map[string]any{"uri": uri, "version": 2},
+		"contentChanges": []map[string]any{{"text": "var a = 1;\nvar b = 2;\nprint a;\n"}},
+	})
+	changed := roundTrip[PublishDiagnosticsParams](t, c.receive()["params"])
+	if len(changed.Diagnostics) != 1 {
+		t.Fatalf("got %v, want one lint warning", changed.Diagnostics)
+	}
+	want := Range{Start: Position{Line: 1, Character: 4}, End: Position{Line: 1, Character: 5}}
+	if d := changed.Diagnostics[0]; d.Severity != severityWarning || d.Code != "unused-variable" || d.Range != want {
+		t.Errorf("got %+v", d)
+	}
+
+	// a scan error is a diagnostic, it doesn't mark the server process as failed
+	errors.HadError = false
+	if typo := c.open("print \"a;\n"); len(typo.Diagnostics) == 0 || errors.HadError {
+		t.Errorf("got %v with HadError %t, want a diagnostic only", typo.Diagnostics, errors.HadError)
+	}
+
+	c.notify("textDocument/didClose", map[string]any{"textDocument": map[string]any{"uri": uri}})
+	if closed := roundTrip[PublishDiagnosticsParams](t, c.receive()["params"]); len(closed.Diagnostics) != 0 {
+		t.Errorf("got %v after close, want no diagnostics", closed.Diagnostics)
+	}
+}
+
+func TestNavigation(t *testing.T) {
+	c := newClient(t)
+	c.open("var a = 1;\n{\n  var a = a + 1;\n  print a;\n}\nprint a;\n")
+
+	// the inner a on line 4 goes to the declaration on line 3
+	definition := roundTrip[Location](t, c.request("textDocument/definition", position(3, 8))["result"])
+	if want := (Range{Start: Position{Line: 2, Character: 6}, End: Position{Line: 2, Character: 7}}); definition.Range != want {
+		t.Errorf("got definition %+v, want %+v", definition.Range, want)
+	}
+
+	params := position(0, 4)
+	params["context"] = map[string]any{"includeDeclaration": true}
+	references := roundTrip[[]Location](t, c.request("textDocument/references", params)["result"])
+	var lines []int
+	for _, location := range references {
+		lines = append(lines, location.Range.Start.Line)
+	}
+	// the initializer on line 3 reads the outer a
+	if want := []int{0, 2, 5}; !slices.Equal(lines, want) {
+		t.Errorf("got references on lines %v, want %v", lines, want)
+	}
+
+	hover := roundTrip[Hover](t, c.request("textDocument/hover", position(2, 6))["result"])
+	if !strings.Contains(hover.Contents.Value, "Shadows the variable declared on line 1") {
+		t.Errorf("got hover %q", hover.Contents.Value)
+	}
+
+	symbols := roundTrip[[]DocumentSymbol](t, c.request("textDocument/documentSymbol", map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+	})["result"])
+	if len(symbols) != 2 || symbols[0].Detail != "global" || symbols[1].Detail != "local" {
+		t.Errorf("got symbols %+v", symbols)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	c := newClient(t)
+	c.open("var outer = 1;\n{\n  var inner = 2;\n  \n}\n\n")
+
+	labels := func(line, character int) map[string]bool {
+		items := roundTrip[[]CompletionItem](t, c.request("textDocument/completion", position(line, character))["result"])
+		set := map[string]bool{}
+		for _, item := range items {
+			set[item.Label] = true
+		}
+		return set
+	}
+
+	inside := labels(3, 2)
+	for _, name := range []string{"outer", "inner", "clock", "print"} {
+		if !inside[name] {
+			t.Errorf("%q missing inside the block", name)
+		}
+	}
+	if outside := labels(5, 0); outside["inner"] || !outside["outer"] {
+		t.Errorf("got %v after the block", outside)
+	}
+}
+
+func TestSemanticTokens(t *testing.T) {
+	c := newClient(t)
+	c.open("var a = 1; // one\nprint \"x\";\n")
+
+	result := roundTrip[SemanticTokens](t, c.request("textDocument/semanticTokens/full", map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+	})["result"])
+
+	want := []int{
+		0, 0, 3, 0, 0, // var
+		0, 4, 1, 1, 0, // a
+		0, 2, 1, 5, 0, // =
+		0, 2, 1, 3, 0, // 1
+		0, 3, 6, 4, 0, // comment
+		1, 0, 5, 0, 0, // print
+		0, 6, 3, 2, 0, // "x"
+	}
+	if !slices.Equal(result.Data, want) {
+		t.Errorf("got %v, want %v", result.Data, want)
+	}
+}
+
+func TestPositionEncoding(t *testing.T) {
+	// 𝒙 is outside the basic multilingual plane, two UTF-16 code units but one rune
+	text := "var s = \"𝒙\"; var a = 1;\nprint a + s;\n"
+
+	for _, test := range []struct {
+		encodings []string
+		want      string
+		start     int
+	}{
+		{nil, "utf-16", 18},
+		{[]string{"utf-8", "utf-32"}, "utf-32", 17},
+	} {
+		c := newClient(t)
+		init := c.request("initialize", map[string]any{"capabilities": map[string]any{
+			"general": map[string]any{"positionEncodings": test.encodings},
+		}})
+		if got := init["result"].(map[string]any)["capabilities"].(map[string]any)["positionEncoding"]; got != test.want {
+			t.Errorf("got encoding %v, want %s", got, test.want)
+		}
+		c.open(text)
+
+		definition := roundTrip[Location](t, c.request("textDocument/definition", position(1, 6))["result"])
+		if want := (Range{Start: Position{Line: 0, Character: test.start}, End: Position{Line: 0, Character: test.start + 1}}); definition.Range != want {
+			t.Errorf("%s: got definition %+v, want %+v", test.want, definition.Range, want)
+		}
+
+		// the position of the client is decoded back to the declaration of a
+		references := roundTrip[[]Location](t, c.request("textDocument/references", map[string]any{
+			"textDocument": map[string]any{"uri": uri},
+			"position":     map[string]any{"line": 0, "character": test.start},
+			"context":      map[string]any{"includeDeclaration": false},
+		})["result"])
+		if len(references) != 1 || references[0].Range.Start.Line != 1 {
+			t.Errorf("%s: got references %+v", test.want, references)
+		}
+
+		semantic := roundTrip[SemanticTokens](t, c.request("textDocument/semanticTokens/full", map[string]any{
+			"textDocument": map[string]any{"uri": uri},
+		})["result"])
+		// the string is the fourth token, its length counts the quotes
+		if got, want := semantic.Data[3*5+2], test.start-14; got != want {
+			t.Errorf("%s: got string length %d, want %d", test.want, got, want)
+		}
+	}
+}
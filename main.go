@@ -1,34 +1,117 @@
 package main
 
 import (
-	"bufio"
+	"flag"
 	"fmt"
 	"golox/errors"
 	"golox/interpreter"
+	"golox/lineedit"
 	"golox/parser"
 	"golox/scanner"
+	"golox/stmt"
+	"golox/tracer"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"unicode/utf8"
 )
 
+// subcommands, any other first argument is treated as a script path
+var commands = map[string]func(args []string){
+	"ast":   astCommand,
+	"bench": benchCommand,
+	"dap":   dapCommand,
+	"debug": debugCommand,
+	"fmt":   fmtCommand,
+	"lint":  lintCommand,
+	"lsp":   lspCommand,
+	"test":  testCommand,
+}
+
 func main() {
-	switch len(os.Args) {
-	case 1:
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			command(os.Args[2:])
+			return
+		}
+	}
+
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: golox [--trace[=file]] [--profile file] [script] | golox <ast|bench|dap|debug|fmt|lint|lsp|test> [flags] <script>")
+		flag.PrintDefaults()
+	}
+	flag.Var(&trace, "trace", "log every executed statement to stderr, or to a file with --trace=file")
+	profile := flag.String("profile", "", "time the script and write a pprof profile to `file`")
+	profileTop := flag.Int("profile-top", 10, "the number of lines and functions in the profile summary")
+	flag.Parse()
+
+	// both are interpreter hooks and only one can be installed
+	if flag.NArg() > 1 || (*profile != "" && (flag.NArg() == 0 || trace.enabled)) {
+		flag.Usage()
+		os.Exit(64)
+	}
+
+	if trace.enabled {
+		interp.Hook = tracer.New(trace.writer())
+	}
+
+	switch {
+	case *profile != "":
+		profileFile(flag.Arg(0), *profile, *profileTop)
+	case flag.NArg() == 1:
+		runFile(flag.Arg(0))
+	default:
 		runPrompt()
-	case 2:
-		runFile(os.Args[1])
+	}
+}
+
+// traceFlag is --trace, it traces to stderr unless a file is given with --trace=file.
+type traceFlag struct {
+	enabled bool
+	path    string
+}
+
+var trace traceFlag
+
+func (t *traceFlag) String() string {
+	return t.path
+}
+
+func (t *traceFlag) Set(value string) error {
+	switch value {
+	case "true":
+		t.enabled, t.path = true, ""
+	case "false":
+		t.enabled, t.path = false, ""
 	default:
-		fmt.Fprintln(os.Stderr, "Usage: golox [script]")
-		os.Exit(64)
+		t.enabled, t.path = true, value
 	}
+	return nil
+}
+
+func (t *traceFlag) IsBoolFlag() bool {
+	return true
+}
+
+func (t *traceFlag) writer() io.Writer {
+	if t.path == "" {
+		return os.Stderr
+	}
+
+	file, err := os.Create(t.path)
+	if err != nil {
+		log.Fatalf("Error creating the trace file: %v", err)
+	}
+	return file
 }
 
 // var interpreter = NewInterpreter()
 var interp = interpreter.NewInterpreter()
 
-func runFile(path string) {
+func readSource(path string) string {
 	extension := filepath.Ext(path)
 	if extension != ".golox" && extension != ".lox" {
 		log.Fatal("Script file must end with .golox or .lox extension")
@@ -39,8 +122,34 @@ func runFile(path string) {
 		log.Fatalf("Error reading file: %v", err)
 	}
 
-	run(string(source))
+	return string(source)
+}
+
+// parseFile scans and parses a script without running it, errors are reported on stderr.
+func parseFile(path string) ([]stmt.Statement[any], bool) {
+	return parseSource(readSource(path))
+}
+
+func parseSource(source string) ([]stmt.Statement[any], bool) {
+	tokens := scanner.NewScanner(source).ScanTokens()
+	if errors.HadError {
+		return nil, false
+	}
+
+	statements, errs := parser.NewParser(tokens).Parse()
+	for _, err := range errs {
+		fmt.Fprintln(os.Stderr, err)
+	}
 
+	return statements, len(errs) == 0
+}
+
+func runFile(path string) {
+	run(readSource(path))
+	exitOnError()
+}
+
+func exitOnError() {
 	// Exit with appropriate error code
 	if errors.HadError {
 		os.Exit(65)
@@ -51,18 +160,25 @@ func runFile(path string) {
 }
 
 func runPrompt() {
-	scanner := bufio.NewScanner(os.Stdin)
+	editor := lineedit.NewEditor(os.Stdin, os.Stdout)
+	editor.Prompt = "> "
+	editor.Complete = complete
 
 	fmt.Println("GoLox REPL - Type 'exit' or 'quit' to quit")
 
 	for {
-		fmt.Print("> ")
-
-		if !scanner.Scan() {
+		input, err := editor.ReadLine()
+		if err == lineedit.ErrInterrupted {
+			continue
+		}
+		if err != nil {
+			if err != io.EOF {
+				fmt.Fprintf(os.Stderr, "Error reading input: %v\n", err)
+			}
 			break
 		}
 
-		line := strings.TrimSpace(scanner.Text())
+		line := strings.TrimSpace(input)
 
 		// Allow user to exit gracefully
 		if line == "exit" || line == "quit" {
@@ -73,6 +189,7 @@ func runPrompt() {
 		if line == "" {
 			continue
 		}
+		editor.AddHistory(line)
 
 		interp.IsRepl = true;	// set IsRepl to true to allow printing value inside the REPL console;
 		run(line)
@@ -81,10 +198,35 @@ func runPrompt() {
 		errors.HadError = false
 		errors.HadRuntimeError = false
 	}
+}
 
-	if err := scanner.Err(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading input: %v\n", err)
+// complete offers the keywords and the variables in scope that start with the word under the cursor.
+func complete(line string, pos int) (int, []string) {
+	// the word is found rune by rune, identifiers aren't only ASCII
+	start := pos
+	for start > 0 {
+		char, size := utf8.DecodeLastRuneInString(line[:start])
+		if !scanner.IsIdentifierContinue(char) {
+			break
+		}
+		start -= size
 	}
+
+	prefix := line[start:pos]
+	first, _ := utf8.DecodeRuneInString(prefix)
+	if prefix == "" || !scanner.IsIdentifierStart(first) || (start > 0 && line[start-1] == '.') {
+		return start, nil
+	}
+
+	var candidates []string
+	for _, name := range append(scanner.Keywords(), interp.Names()...) {
+		if strings.HasPrefix(name, prefix) && !slices.Contains(candidates, name) {
+			candidates = append(candidates, name)
+		}
+	}
+	slices.Sort(candidates)
+
+	return start, candidates
 }
 
 func run(source string) {
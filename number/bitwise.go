@@ -0,0 +1,90 @@
+package number
+
+import (
+	"errors"
+	"math/big"
+)
+
+var (
+	// ErrNegativeShift is returned by the shifts when the count is negative.
+	ErrNegativeShift = errors.New("Negative shift count")
+	// ErrShiftTooLarge is returned by the shifts when the count doesn't fit an int64.
+	ErrShiftTooLarge = errors.New("Shift count too large")
+)
+
+// The bitwise operations treat integers as two's complement numbers with infinitely
+// many sign bits, so -1 has every bit set whatever its representation. They panic
+// when an operand isn't an integer.
+
+func And(a, b any) any {
+	if x, y, ok := int64s(a, b); ok {
+		return x & y
+	}
+	return Normalize(new(big.Int).And(toBig(a), toBig(b)))
+}
+
+func Or(a, b any) any {
+	if x, y, ok := int64s(a, b); ok {
+		return x | y
+	}
+	return Normalize(new(big.Int).Or(toBig(a), toBig(b)))
+}
+
+func Xor(a, b any) any {
+	if x, y, ok := int64s(a, b); ok {
+		return x ^ y
+	}
+	return Normalize(new(big.Int).Xor(toBig(a), toBig(b)))
+}
+
+// Not flips every bit, ~x is -x - 1.
+func Not(a any) any {
+	if x, ok := a.(int64); ok {
+		return ^x
+	}
+	return Normalize(new(big.Int).Not(toBig(a)))
+}
+
+// ShiftLeft multiplies a by 2 to the power of count, it fails with ErrTooLarge past MaxBits.
+func ShiftLeft(a, count any) (any, error) {
+	n, err := shiftCount(count)
+	if err != nil {
+		return nil, err
+	}
+
+	if x, ok := a.(int64); ok && n < 63 {
+		// the shift is exact when shifting back gives x again
+		if shifted := x << n; shifted>>n == x {
+			return shifted, nil
+		}
+	}
+	x := toBig(a)
+	if x.Sign() != 0 && (n > MaxBits || uint(x.BitLen()) > MaxBits-n) {
+		return nil, ErrTooLarge
+	}
+	return Normalize(new(big.Int).Lsh(x, n)), nil
+}
+
+// ShiftRight divides a by 2 to the power of count, rounding toward negative infinity.
+func ShiftRight(a, count any) (any, error) {
+	n, err := shiftCount(count)
+	if err != nil {
+		return nil, err
+	}
+
+	if x, ok := a.(int64); ok {
+		return x >> min(n, 63), nil
+	}
+	return Normalize(new(big.Int).Rsh(toBig(a), n)), nil
+}
+
+func shiftCount(count any) (uint, error) {
+	n, ok := count.(int64)
+	switch {
+	case !ok && toBig(count).Sign() < 0, ok && n < 0:
+		return 0, ErrNegativeShift
+	case !ok:
+		return 0, ErrShiftTooLarge
+	}
+	return uint(n), nil
+}
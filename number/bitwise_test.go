@@ -0,0 +1,39 @@
+package number
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestBitwise(t *testing.T) {
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"and", And(int64(12), int64(10)), int64(8)},
+		{"or big", Or(bigInt("18446744073709551616"), int64(1)), bigInt("18446744073709551617")},
+		{"xor back to int", Xor(bigInt("18446744073709551617"), bigInt("18446744073709551616")), int64(1)},
+		{"and negative big", And(int64(-1), bigInt("18446744073709551616")), bigInt("18446744073709551616")},
+		{"not", Not(int64(5)), int64(-6)},
+		{"not big", Not(bigInt("-18446744073709551617")), bigInt("18446744073709551616")},
+		{"left", result(ShiftLeft(int64(3), int64(4))), int64(48)},
+		{"left overflow", result(ShiftLeft(int64(math.MaxInt64), int64(1))), bigInt("18446744073709551614")},
+		{"left most negative", result(ShiftLeft(int64(-1), int64(63))), int64(math.MinInt64)},
+		{"right negative", result(ShiftRight(int64(-9), int64(1))), int64(-5)},
+		{"right past the width", result(ShiftRight(int64(-9), int64(200))), int64(-1)},
+		{"right big", result(ShiftRight(bigInt("18446744073709551616"), int64(60))), int64(16)},
+		{"negative count", result(ShiftLeft(int64(1), int64(-1))), ErrNegativeShift},
+		{"huge count", result(ShiftRight(int64(1), bigInt("18446744073709551616"))), ErrShiftTooLarge},
+		{"left past the limit", result(ShiftLeft(int64(3), int64(MaxBits-1))), ErrTooLarge},
+		{"left of zero", result(ShiftLeft(int64(0), int64(10000000000))), int64(0)},
+	}
+
+	for _, test := range tests {
+		if reflect.TypeOf(test.got) != reflect.TypeOf(test.want) || fmt.Sprint(test.got) != fmt.Sprint(test.want) {
+			t.Errorf("%s: got %T %v, want %T %v", test.name, test.got, test.got, test.want, test.want)
+		}
+	}
+}
@@ -0,0 +1,398 @@
+package number
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var ten = big.NewInt(10)
+
+// MaxScale bounds the decimals: a decimal has at most MaxScale digits after its point
+// and is written with an exponent from -MaxScale to MaxScale. Past it the digits to
+// compute would take too long or exhaust the memory.
+const MaxScale = 10000
+
+var (
+	// ErrDecimalExponent is returned by Power when a decimal is raised to a power with a fraction.
+	ErrDecimalExponent = errors.New("A decimal can only be raised to an integer power")
+	// ErrInvalidDecimal is returned by ParseDecimal for a text that isn't a decimal.
+	ErrInvalidDecimal = errors.New("Invalid decimal")
+	// ErrDecimalScale is returned when a decimal would go past MaxScale.
+	ErrDecimalScale = fmt.Errorf("A decimal can't have more than %d digits after its point or an exponent past %d", MaxScale, MaxScale)
+)
+
+// Decimal is an exact decimal number: its coefficient divided by 10 to the power of
+// its scale. The scale is the number of digits after the decimal point and is kept
+// through the operations, so 1.10d + 1 is 2.10d. The zero Decimal isn't valid, decimals
+// come from ParseDecimal and the operations of the package.
+type Decimal struct {
+	coefficient *big.Int
+	scale       int
+}
+
+// ParseDecimal parses a decimal written with an optional sign, digits, an optional
+// fraction and an optional exponent, like 12.34 or -1.5e3. It fails with ErrDecimalScale
+// when the decimal goes past MaxScale and with ErrInvalidDecimal on any other text.
+func ParseDecimal(text string) (Decimal, error) {
+	mantissa, exponent := text, 0
+	if i := strings.IndexAny(text, "eE"); i >= 0 {
+		e, err := strconv.Atoi(text[i+1:])
+		if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+			return Decimal{}, ErrDecimalScale
+		}
+		if err != nil {
+			return Decimal{}, ErrInvalidDecimal
+		}
+		mantissa, exponent = text[:i], e
+	}
+
+	sign := ""
+	if mantissa != "" && (mantissa[0] == '-' || mantissa[0] == '+') {
+		sign, mantissa = mantissa[:1], mantissa[1:]
+	}
+	whole, fraction, _ := strings.Cut(mantissa, ".")
+	digits := whole + fraction
+	if digits == "" || strings.ContainsFunc(digits, func(char rune) bool { return char < '0' || char > '9' }) {
+		return Decimal{}, ErrInvalidDecimal
+	}
+	scale := len(fraction) - exponent
+	if exponent < -MaxScale || exponent > MaxScale || scale > MaxScale {
+		return Decimal{}, ErrDecimalScale
+	}
+
+	coefficient, _ := new(big.Int).SetString(sign+digits, 10)
+	return newDecimal(coefficient, scale), nil
+}
+
+// newDecimal returns coefficient * 10^-scale, a negative scale is brought back to 0.
+func newDecimal(coefficient *big.Int, scale int) Decimal {
+	if scale < 0 {
+		coefficient = new(big.Int).Mul(coefficient, pow10(-scale))
+		scale = 0
+	}
+	return Decimal{coefficient: coefficient, scale: scale}
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(ten, big.NewInt(int64(n)), nil)
+}
+
+// ToDecimal converts a number to a decimal. A float is converted from the shortest
+// text that reads back to it, so 0.1 gives 0.1d rather than its exact binary value.
+func ToDecimal(value any) (Decimal, bool) {
+	switch v := value.(type) {
+	case Decimal:
+		return v, true
+	case int64, *big.Int:
+		return Decimal{coefficient: toBig(v), scale: 0}, true
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return Decimal{}, false
+		}
+		d, err := ParseDecimal(strconv.FormatFloat(v, 'e', -1, 64))
+		return d, err == nil
+	}
+	return Decimal{}, false
+}
+
+// String writes every digit of the scale, 1.50d is "1.50".
+func (d Decimal) String() string {
+	digits := new(big.Int).Abs(d.coefficient).String()
+	if len(digits) <= d.scale {
+		digits = strings.Repeat("0", d.scale-len(digits)+1) + digits
+	}
+
+	sign := ""
+	if d.coefficient.Sign() < 0 {
+		sign = "-"
+	}
+	if d.scale == 0 {
+		return sign + digits
+	}
+	point := len(digits) - d.scale
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+func (d Decimal) rat() *big.Rat {
+	return new(big.Rat).SetFrac(d.coefficient, pow10(d.scale))
+}
+
+// align returns the coefficients of two decimals brought to the larger scale.
+func align(x, y Decimal) (*big.Int, *big.Int, int) {
+	switch {
+	case x.scale < y.scale:
+		return new(big.Int).Mul(x.coefficient, pow10(y.scale-x.scale)), y.coefficient, y.scale
+	case x.scale > y.scale:
+		return x.coefficient, new(big.Int).Mul(y.coefficient, pow10(x.scale-y.scale)), x.scale
+	}
+	return x.coefficient, y.coefficient, x.scale
+}
+
+// decimals returns the operands as decimals when one is a decimal and the other an integer or a decimal.
+func decimals(a, b any) (Decimal, Decimal, bool) {
+	_, aok := a.(Decimal)
+	_, bok := b.(Decimal)
+	if !aok && !bok {
+		return Decimal{}, Decimal{}, false
+	}
+
+	x, xok := a.(Decimal)
+	if !xok && IsInteger(a) {
+		x, xok = Decimal{coefficient: toBig(a)}, true
+	}
+	y, yok := b.(Decimal)
+	if !yok && IsInteger(b) {
+		y, yok = Decimal{coefficient: toBig(b)}, true
+	}
+	return x, y, xok && yok
+}
+
+// CanMix reports whether arithmetic can combine two numbers: decimals don't mix
+// with floats, the result would be neither exact nor a float.
+func CanMix(a, b any) bool {
+	_, aDecimal := a.(Decimal)
+	_, bDecimal := b.(Decimal)
+	_, aFloat := a.(float64)
+	_, bFloat := b.(float64)
+	return !(aDecimal && bFloat) && !(aFloat && bDecimal)
+}
+
+// Rounding is how a decimal division rounds the digits past the precision.
+type Rounding int
+
+const (
+	// HalfEven rounds to the nearest neighbour, and to the even one on a tie
+	HalfEven Rounding = iota
+	// HalfUp rounds to the nearest neighbour, and away from zero on a tie
+	HalfUp
+	// HalfDown rounds to the nearest neighbour, and toward zero on a tie
+	HalfDown
+	// Up rounds away from zero
+	Up
+	// Down rounds toward zero, truncating
+	Down
+	// Ceiling rounds toward positive infinity
+	Ceiling
+	// Floor rounds toward negative infinity
+	Floor
+)
+
+var roundingNames = map[Rounding]string{
+	HalfEven: "half_even",
+	HalfUp:   "half_up",
+	HalfDown: "half_down",
+	Up:       "up",
+	Down:     "down",
+	Ceiling:  "ceiling",
+	Floor:    "floor",
+}
+
+func (r Rounding) String() string {
+	return roundingNames[r]
+}
+
+// ParseRounding returns the rounding mode with the given name, as printed by Rounding.String.
+func ParseRounding(name string) (Rounding, bool) {
+	for rounding, roundingName := range roundingNames {
+		if roundingName == name {
+			return rounding, true
+		}
+	}
+	return HalfEven, false
+}
+
+// Context holds the settings of decimal division, the only decimal operation that
+// can't always be exact.
+type Context struct {
+	// Precision is the number of significant digits an inexact quotient is rounded to,
+	// digits before the decimal point are never rounded away
+	Precision int
+	Rounding  Rounding
+}
+
+// DefaultContext is the context of Divide.
+var DefaultContext = Context{Precision: 28, Rounding: HalfEven}
+
+// Divide divides with the context of the package, see Context.Divide.
+func Divide(a, b any) (any, error) {
+	return DefaultContext.Divide(a, b)
+}
+
+// Divide divides exactly. The quotient of two integers is an integer when the
+// division leaves no remainder, and the float nearest to the exact fraction otherwise.
+// The quotient of decimals is exact when it has at most c.Precision significant
+// digits, and rounded to them with c.Rounding otherwise.
+func (c Context) Divide(a, b any) (any, error) {
+	if isZero(b) {
+		return nil, ErrDivisionByZero
+	}
+
+	if IsInteger(a) && IsInteger(b) {
+		x, y := toBig(a), toBig(b)
+		quotient, remainder := new(big.Int).QuoRem(x, y, new(big.Int))
+		if remainder.Sign() == 0 {
+			return Normalize(quotient), nil
+		}
+		f, _ := new(big.Rat).SetFrac(x, y).Float64()
+		return f, nil
+	}
+	if x, y, ok := decimals(a, b); ok {
+		return c.divideDecimals(x, y)
+	}
+	return toFloat(a) / toFloat(b), nil
+}
+
+// Power raises to a power with the context of the package, see Context.Power.
+func Power(a, b any) (any, error) {
+	return DefaultContext.Power(a, b)
+}
+
+// Power raises a to the power of b. An integer raised to a non-negative integer is
+// an exact integer, to a negative one a float. A decimal can only be raised to an
+// integer power, the result is exact unless the power is negative: it is then
+// divided with the context. Exact powers fail with ErrTooLarge past MaxBits, and
+// decimal ones with ErrDecimalScale past MaxScale.
+func (c Context) Power(a, b any) (any, error) {
+	if isZero(a) && sign(b) < 0 {
+		return nil, ErrDivisionByZero
+	}
+
+	if x, y, ok := decimals(a, b); ok {
+		y = trimZeros(y, 0)
+		if y.scale != 0 {
+			return nil, ErrDecimalExponent
+		}
+		exponent := new(big.Int).Abs(y.coefficient)
+		// the scale is multiplied by the exponent, checked by division against an overflow
+		if x.scale > 0 && (!exponent.IsInt64() || exponent.Int64() > int64(MaxScale/x.scale)) {
+			return nil, ErrDecimalScale
+		}
+		coefficient, err := power(x.coefficient, exponent)
+		if err != nil {
+			return nil, err
+		}
+		result := Decimal{coefficient: coefficient, scale: x.scale * int(exponent.Int64())}
+		if y.coefficient.Sign() < 0 {
+			return c.Divide(int64(1), result)
+		}
+		return result, nil
+	}
+
+	if IsInteger(a) && IsInteger(b) && sign(b) >= 0 {
+		p, err := power(toBig(a), toBig(b))
+		if err != nil {
+			return nil, err
+		}
+		return Normalize(p), nil
+	}
+	return math.Pow(toFloat(a), toFloat(b)), nil
+}
+
+// power returns x to the power of a non-negative n, it fails with ErrTooLarge past MaxBits.
+func power(x, n *big.Int) (*big.Int, error) {
+	// 0, 1 and -1 keep their size whatever the power
+	if x.BitLen() <= 1 {
+		return new(big.Int).Exp(x, n, nil), nil
+	}
+
+	// the power has more than (x.BitLen() - 1) * n bits
+	if !n.IsInt64() || n.Int64() > int64(MaxBits/(x.BitLen()-1)) {
+		return nil, ErrTooLarge
+	}
+	p := new(big.Int).Exp(x, n, nil)
+	if p.BitLen() > MaxBits {
+		return nil, ErrTooLarge
+	}
+	return p, nil
+}
+
+// divideDecimals fails with ErrDecimalScale when the quotient needs more than MaxScale
+// digits after its point, a quotient too small for its significant digits to fit.
+func (c Context) divideDecimals(x, y Decimal) (Decimal, error) {
+	// x / y is numerator / denominator, an integer quotient of them has scale 0
+	numerator := new(big.Int).Mul(x.coefficient, pow10(y.scale))
+	denominator := new(big.Int).Mul(y.coefficient, pow10(x.scale))
+
+	// the number of digits before the point, counted from the lengths of the operands,
+	// it can be one short, then the quotient has a digit too many and is divided again
+	integerDigits := digitCount(numerator) - digitCount(denominator)
+	for {
+		scale := min(max(c.Precision-integerDigits, 0), MaxScale)
+		scaled := new(big.Int).Mul(numerator, pow10(scale))
+		quotient, exact := roundQuotient(scaled, denominator, c.Rounding)
+
+		if digitCount(quotient) > c.Precision && scale > 0 {
+			integerDigits++
+			continue
+		}
+		if exact {
+			// drop the zeros past the scale of the dividend, 1.50d / 3 is 0.50d
+			return trimZeros(Decimal{coefficient: quotient, scale: scale}, max(x.scale-y.scale, 0)), nil
+		}
+		if scale < c.Precision-integerDigits {
+			return Decimal{}, ErrDecimalScale
+		}
+		return Decimal{coefficient: quotient, scale: scale}, nil
+	}
+}
+
+func digitCount(n *big.Int) int {
+	if n.Sign() == 0 {
+		return 1
+	}
+	return len(new(big.Int).Abs(n).String())
+}
+
+// roundQuotient divides and rounds the quotient to an integer, reporting whether the division was exact.
+func roundQuotient(numerator, denominator *big.Int, rounding Rounding) (*big.Int, bool) {
+	quotient, remainder := new(big.Int).QuoRem(numerator, denominator, new(big.Int))
+	if remainder.Sign() == 0 {
+		return quotient, true
+	}
+
+	sign := numerator.Sign() * denominator.Sign()
+	// how the remainder compares to half the denominator
+	half := new(big.Int).Abs(new(big.Int).Lsh(remainder, 1)).Cmp(new(big.Int).Abs(denominator))
+
+	var away bool
+	switch rounding {
+	case HalfEven:
+		away = half > 0 || (half == 0 && quotient.Bit(0) == 1)
+	case HalfUp:
+		away = half >= 0
+	case HalfDown:
+		away = half > 0
+	case Up:
+		away = true
+	case Down:
+		away = false
+	case Ceiling:
+		away = sign > 0
+	case Floor:
+		away = sign < 0
+	}
+
+	if away {
+		quotient.Add(quotient, big.NewInt(int64(sign)))
+	}
+	return quotient, false
+}
+
+// trimZeros removes the trailing zeros of a decimal down to the given scale.
+func trimZeros(d Decimal, scale int) Decimal {
+	coefficient := new(big.Int).Set(d.coefficient)
+	remainder := new(big.Int)
+	for d.scale > scale {
+		quotient, _ := new(big.Int).QuoRem(coefficient, ten, remainder)
+		if remainder.Sign() != 0 {
+			break
+		}
+		coefficient = quotient
+		d.scale--
+	}
+	return Decimal{coefficient: coefficient, scale: d.scale}
+}
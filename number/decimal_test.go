@@ -0,0 +1,143 @@
+package number
+
+import (
+	"strings"
+	"testing"
+)
+
+func decimal(text string) Decimal {
+	d, err := ParseDecimal(text)
+	if err != nil {
+		panic("invalid decimal " + text)
+	}
+	return d
+}
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"12.34", "12.34"},
+		{"-0.50", "-0.50"},
+		{".5", "0.5"},
+		{"7.", "7"},
+		{"1.5e3", "1500"},
+		{"25e-4", "0.0025"},
+		{"+3", "3"},
+	}
+
+	for _, test := range tests {
+		d, err := ParseDecimal(test.text)
+		if err != nil || d.String() != test.want {
+			t.Errorf("ParseDecimal(%q) = %v, %v, want %s", test.text, d, err, test.want)
+		}
+	}
+
+	for _, text := range []string{"", ".", "1.2.3", "1e", "12a", "--1", "0x10"} {
+		if _, err := ParseDecimal(text); err != ErrInvalidDecimal {
+			t.Errorf("ParseDecimal(%q) = %v, want ErrInvalidDecimal", text, err)
+		}
+	}
+	for _, text := range []string{"1e10001", "1e-10001", "1e999999999999999999999", "0." + strings.Repeat("0", MaxScale) + "1"} {
+		if _, err := ParseDecimal(text); err != ErrDecimalScale {
+			t.Errorf("ParseDecimal(%.20q) = %v, want ErrDecimalScale", text, err)
+		}
+	}
+}
+
+func TestDecimalArithmetic(t *testing.T) {
+	tests := []struct {
+		name string
+		got  any
+		want string
+	}{
+		{"sum", Add(decimal("0.1"), decimal("0.2")), "0.3"},
+		{"scale kept", Add(decimal("1.10"), int64(1)), "2.10"},
+		{"difference", Subtract(int64(10), decimal("0.01")), "9.99"},
+		{"product", result(Multiply(decimal("1.5"), decimal("1.5"))), "2.25"},
+		{"negation", Negate(decimal("2.50")), "-2.50"},
+	}
+
+	for _, test := range tests {
+		if got, ok := test.got.(Decimal); !ok || got.String() != test.want {
+			t.Errorf("%s: got %T %v, want %s", test.name, test.got, test.got, test.want)
+		}
+	}
+}
+
+func TestDecimalDivision(t *testing.T) {
+	tests := []struct {
+		context Context
+		a, b    any
+		want    string
+	}{
+		{DefaultContext, decimal("1.50"), int64(3), "0.50"},
+		{DefaultContext, int64(1), decimal("3"), "0.3333333333333333333333333333"},
+		{DefaultContext, decimal("100"), decimal("0.001"), "100000"},
+		{Context{4, HalfEven}, decimal("2"), decimal("3"), "0.6667"},
+		{Context{4, Down}, decimal("2"), decimal("3"), "0.6666"},
+		{Context{2, HalfEven}, decimal("1"), int64(8), "0.12"},
+		{Context{2, HalfUp}, decimal("1"), int64(8), "0.13"},
+		{Context{2, HalfDown}, decimal("1"), int64(8), "0.12"},
+		{Context{2, Up}, decimal("-1"), int64(3), "-0.34"},
+		{Context{2, Ceiling}, decimal("-1"), int64(3), "-0.33"},
+		{Context{2, Floor}, decimal("1"), int64(3), "0.33"},
+		// rounding up carries into a new digit
+		{Context{3, HalfEven}, decimal("9.9999"), int64(1), "10.0"},
+		// digits before the point aren't rounded away
+		{Context{2, HalfEven}, decimal("12345.6"), int64(1), "12346"},
+	}
+
+	for _, test := range tests {
+		got, err := test.context.Divide(test.a, test.b)
+		if d, ok := got.(Decimal); err != nil || !ok || d.String() != test.want {
+			t.Errorf("%v / %v with %v: got %T %v (%v), want %s", test.a, test.b, test.context, got, got, err, test.want)
+		}
+	}
+
+	if _, err := Divide(decimal("1"), decimal("0.00")); err != ErrDivisionByZero {
+		t.Errorf("1d / 0.00d: got error %v, want %v", err, ErrDivisionByZero)
+	}
+}
+
+func TestDecimalScaleLimit(t *testing.T) {
+	smallest := decimal("1e-10000")
+	if got, err := Divide(decimal("1e-9999"), int64(10)); err != nil || !Equal(got, smallest) {
+		t.Errorf("an exact quotient at the limit: got %v (%v), want %v", got, err, smallest)
+	}
+	if _, err := Divide(smallest, int64(3)); err != ErrDecimalScale {
+		t.Errorf("an inexact quotient past the limit: got error %v, want %v", err, ErrDecimalScale)
+	}
+	if _, err := Multiply(smallest, decimal("0.1")); err != ErrDecimalScale {
+		t.Errorf("a product past the limit: got error %v, want %v", err, ErrDecimalScale)
+	}
+}
+
+func TestDecimalFloorDivision(t *testing.T) {
+	quotient, _ := FloorDivide(decimal("-7.5"), int64(2))
+	remainder, _ := Modulo(decimal("-7.5"), int64(2))
+	if Format(quotient) != "-4" || Format(remainder) != "0.5" {
+		t.Errorf("-7.5d floor divided by 2: got %v remainder %v, want -4 remainder 0.5", quotient, remainder)
+	}
+}
+
+func TestDecimalMixing(t *testing.T) {
+	if !CanMix(decimal("1"), int64(1)) || CanMix(decimal("1"), 1.0) || CanMix(1.0, decimal("1")) {
+		t.Error("decimals must mix with integers only")
+	}
+
+	tests := []struct {
+		a, b any
+		want int
+	}{
+		{decimal("0.1"), 0.1, -1},
+		{decimal("2.50"), decimal("2.5"), 0},
+		{decimal("3"), int64(3), 0},
+		{bigInt("1000000000000000000000000000000"), decimal("999999999999999999999999999999.9"), 1},
+	}
+	for _, test := range tests {
+		if got, ordered := Compare(test.a, test.b); got != test.want || !ordered {
+			t.Errorf("Compare(%v, %v) = %d, %t, want %d", test.a, test.b, got, ordered, test.want)
+		}
+	}
+}
@@ -0,0 +1,325 @@
+// Package number implements the arithmetic of Lox numbers.
+//
+// Integers are int64 values, promoted to *big.Int when they overflow and brought
+// back to int64 as soon as they fit again, so an integer that fits an int64 is
+// never a *big.Int. Numbers with a fraction or an exponent are float64 values, and
+// an operation mixing integers and floats is done on floats. Decimals are exact and
+// mix with integers, but not with floats, see CanMix.
+package number
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// MaxBits bounds the powers and the left shifts, whose results grow the fastest:
+// their integers and decimal coefficients have at most MaxBits bits.
+const MaxBits = 1 << 20
+
+var (
+	// ErrDivisionByZero is returned by the divisions when the divisor is zero.
+	ErrDivisionByZero = errors.New("Division by zero")
+	// ErrTooLarge is returned by the powers and the left shifts past MaxBits.
+	ErrTooLarge = fmt.Errorf("Result too large, it would have more than %d bits", MaxBits)
+)
+
+// IsNumber reports whether value is a Lox number.
+func IsNumber(value any) bool {
+	switch value.(type) {
+	case int64, *big.Int, float64, Decimal:
+		return true
+	}
+	return false
+}
+
+// IsInteger reports whether value is a Lox integer, whatever its size.
+func IsInteger(value any) bool {
+	switch value.(type) {
+	case int64, *big.Int:
+		return true
+	}
+	return false
+}
+
+// Normalize returns n as an int64 when it fits one.
+func Normalize(n *big.Int) any {
+	if n.IsInt64() {
+		return n.Int64()
+	}
+	return n
+}
+
+// ParseInt parses an integer written in base, with an optional sign but no prefix or separators.
+func ParseInt(digits string, base int) (any, bool) {
+	n, ok := new(big.Int).SetString(digits, base)
+	if !ok {
+		return nil, false
+	}
+	return Normalize(n), true
+}
+
+func toBig(value any) *big.Int {
+	switch v := value.(type) {
+	case int64:
+		return big.NewInt(v)
+	case *big.Int:
+		return v
+	}
+	panic("number: not an integer")
+}
+
+func toFloat(value any) float64 {
+	switch v := value.(type) {
+	case int64:
+		return float64(v)
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(v).Float64()
+		return f
+	case float64:
+		return v
+	case Decimal:
+		f, _ := v.rat().Float64()
+		return f
+	}
+	panic("number: not a number")
+}
+
+// int64s returns the operands when both are int64.
+func int64s(a, b any) (int64, int64, bool) {
+	x, xok := a.(int64)
+	y, yok := b.(int64)
+	return x, y, xok && yok
+}
+
+// The operations below panic when an operand isn't a number or when the operands can't be mixed.
+
+func Add(a, b any) any {
+	if x, y, ok := int64s(a, b); ok {
+		if sum := x + y; (sum > x) == (y > 0) {
+			return sum
+		}
+	}
+	if IsInteger(a) && IsInteger(b) {
+		return Normalize(new(big.Int).Add(toBig(a), toBig(b)))
+	}
+	if x, y, ok := decimals(a, b); ok {
+		xc, yc, scale := align(x, y)
+		return Decimal{coefficient: new(big.Int).Add(xc, yc), scale: scale}
+	}
+	return toFloat(a) + toFloat(b)
+}
+
+func Subtract(a, b any) any {
+	if x, y, ok := int64s(a, b); ok {
+		if difference := x - y; (difference < x) == (y > 0) {
+			return difference
+		}
+	}
+	if IsInteger(a) && IsInteger(b) {
+		return Normalize(new(big.Int).Sub(toBig(a), toBig(b)))
+	}
+	if x, y, ok := decimals(a, b); ok {
+		xc, yc, scale := align(x, y)
+		return Decimal{coefficient: new(big.Int).Sub(xc, yc), scale: scale}
+	}
+	return toFloat(a) - toFloat(b)
+}
+
+// Multiply fails with ErrDecimalScale when the product of decimals has more than MaxScale digits after its point.
+func Multiply(a, b any) (any, error) {
+	if x, y, ok := int64s(a, b); ok {
+		if x == 0 || y == 0 {
+			return int64(0), nil
+		}
+		// the product of the most negative int64 and -1 overflows without failing the check
+		product := x * y
+		if product/y == x && !(x == -1 && y == math.MinInt64) && !(y == -1 && x == math.MinInt64) {
+			return product, nil
+		}
+	}
+	if IsInteger(a) && IsInteger(b) {
+		return Normalize(new(big.Int).Mul(toBig(a), toBig(b))), nil
+	}
+	if x, y, ok := decimals(a, b); ok {
+		if x.scale+y.scale > MaxScale {
+			return nil, ErrDecimalScale
+		}
+		return Decimal{coefficient: new(big.Int).Mul(x.coefficient, y.coefficient), scale: x.scale + y.scale}, nil
+	}
+	return toFloat(a) * toFloat(b), nil
+}
+
+// FloorDivide divides and rounds the quotient toward negative infinity, the
+// quotient of two integers is an integer and the one of decimals a decimal without fraction.
+func FloorDivide(a, b any) (any, error) {
+	if isZero(b) {
+		return nil, ErrDivisionByZero
+	}
+
+	if IsInteger(a) && IsInteger(b) {
+		quotient, _ := floorQuoRem(toBig(a), toBig(b))
+		return Normalize(quotient), nil
+	}
+	if x, y, ok := decimals(a, b); ok {
+		xc, yc, _ := align(x, y)
+		quotient, _ := floorQuoRem(xc, yc)
+		return Decimal{coefficient: quotient, scale: 0}, nil
+	}
+	return math.Floor(toFloat(a) / toFloat(b)), nil
+}
+
+// Modulo returns the remainder of FloorDivide, which has the sign of the divisor:
+// a == FloorDivide(a, b) * b + Modulo(a, b).
+func Modulo(a, b any) (any, error) {
+	if isZero(b) {
+		return nil, ErrDivisionByZero
+	}
+
+	if IsInteger(a) && IsInteger(b) {
+		_, remainder := floorQuoRem(toBig(a), toBig(b))
+		return Normalize(remainder), nil
+	}
+	if x, y, ok := decimals(a, b); ok {
+		xc, yc, scale := align(x, y)
+		_, remainder := floorQuoRem(xc, yc)
+		return Decimal{coefficient: remainder, scale: scale}, nil
+	}
+
+	x, y := toFloat(a), toFloat(b)
+	remainder := math.Mod(x, y)
+	if remainder != 0 && (remainder < 0) != (y < 0) {
+		remainder += y
+	}
+	return remainder, nil
+}
+
+// floorQuoRem turns the truncated division of big.Int into a floored one.
+func floorQuoRem(x, y *big.Int) (*big.Int, *big.Int) {
+	quotient, remainder := new(big.Int).QuoRem(x, y, new(big.Int))
+	if remainder.Sign() != 0 && (remainder.Sign() < 0) != (y.Sign() < 0) {
+		quotient.Sub(quotient, big.NewInt(1))
+		remainder.Add(remainder, y)
+	}
+	return quotient, remainder
+}
+
+func sign(value any) int {
+	switch v := value.(type) {
+	case int64:
+		return cmp.Compare(v, 0)
+	case *big.Int:
+		return v.Sign()
+	case Decimal:
+		return v.coefficient.Sign()
+	}
+	// NaN has no sign
+	switch f := toFloat(value); {
+	case f > 0:
+		return 1
+	case f < 0:
+		return -1
+	}
+	return 0
+}
+
+func isZero(value any) bool {
+	switch v := value.(type) {
+	case int64:
+		return v == 0
+	case *big.Int:
+		return v.Sign() == 0
+	case Decimal:
+		return v.coefficient.Sign() == 0
+	}
+	return toFloat(value) == 0
+}
+
+func Negate(a any) any {
+	switch v := a.(type) {
+	case int64:
+		if v != math.MinInt64 {
+			return -v
+		}
+		return new(big.Int).Neg(big.NewInt(v))
+	case *big.Int:
+		return Normalize(new(big.Int).Neg(v))
+	case Decimal:
+		return Decimal{coefficient: new(big.Int).Neg(v.coefficient), scale: v.scale}
+	}
+	return -toFloat(a)
+}
+
+// Compare returns -1, 0 or +1 as a is less than, equal to or greater than b,
+// whatever their representations. The numbers are unordered when one is NaN.
+func Compare(a, b any) (result int, ordered bool) {
+	if x, y, ok := int64s(a, b); ok {
+		return cmp.Compare(x, y), true
+	}
+	if IsInteger(a) && IsInteger(b) {
+		return toBig(a).Cmp(toBig(b)), true
+	}
+
+	fa, aFloat := a.(float64)
+	fb, bFloat := b.(float64)
+	if (aFloat && math.IsNaN(fa)) || (bFloat && math.IsNaN(fb)) {
+		return 0, false
+	}
+	// infinities have no exact value, only a float can be as large
+	switch {
+	case aFloat && bFloat:
+		return cmp.Compare(fa, fb), true
+	case aFloat && math.IsInf(fa, 0):
+		return int(math.Copysign(1, fa)), true
+	case bFloat && math.IsInf(fb, 0):
+		return -int(math.Copysign(1, fb)), true
+	}
+	// the numbers are compared exactly rather than rounded to the nearest floats
+	return toRat(a).Cmp(toRat(b)), true
+}
+
+func toRat(value any) *big.Rat {
+	switch v := value.(type) {
+	case float64:
+		return new(big.Rat).SetFloat64(v)
+	case Decimal:
+		return v.rat()
+	}
+	return new(big.Rat).SetInt(toBig(value))
+}
+
+// Equal reports whether two numbers have the same value, so 1 equals 1.0.
+func Equal(a, b any) bool {
+	result, ordered := Compare(a, b)
+	return ordered && result == 0
+}
+
+// Format returns the text print shows for a number. Floats are written with the
+// fewest digits that read back to the same value, in scientific notation when
+// very large or very small, decimals with every digit of their scale.
+func Format(value any) string {
+	switch v := value.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case *big.Int:
+		return v.String()
+	case Decimal:
+		return v.String()
+	}
+
+	f := toFloat(value)
+	switch {
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	case math.IsNaN(f):
+		return "NaN"
+	case f != 0 && (math.Abs(f) < 1e-6 || math.Abs(f) >= 1e21):
+		return strconv.FormatFloat(f, 'e', -1, 64)
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
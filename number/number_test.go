@@ -0,0 +1,158 @@
+package number
+
+import (
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func bigInt(text string) *big.Int {
+	n, _ := new(big.Int).SetString(text, 10)
+	return n
+}
+
+// result returns the error of an operation that failed and its result otherwise.
+func result(value any, err error) any {
+	if err != nil {
+		return err
+	}
+	return value
+}
+
+// tenth keeps 0.1 + 0.2 from being folded exactly by the compiler
+var tenth = 0.1
+
+func TestArithmetic(t *testing.T) {
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"int sum", Add(int64(1), int64(2)), int64(3)},
+		{"sum overflow", Add(int64(math.MaxInt64), int64(1)), bigInt("9223372036854775808")},
+		{"difference overflow", Subtract(int64(math.MinInt64), int64(1)), bigInt("-9223372036854775809")},
+		{"product overflow", result(Multiply(int64(math.MaxInt64), int64(2))), bigInt("18446744073709551614")},
+		{"most negative times -1", result(Multiply(int64(math.MinInt64), int64(-1))), bigInt("9223372036854775808")},
+		{"negate most negative", Negate(int64(math.MinInt64)), bigInt("9223372036854775808")},
+		{"big back to int", Subtract(bigInt("9223372036854775808"), int64(1)), int64(math.MaxInt64)},
+		{"mixed sum", Add(int64(1), 0.5), 1.5},
+		{"float sum", Add(tenth, 0.2), 0.30000000000000004},
+		{"big times float", result(Multiply(bigInt("9223372036854775808"), 2.0)), 18446744073709551616.0},
+	}
+
+	for _, test := range tests {
+		if reflect.TypeOf(test.got) != reflect.TypeOf(test.want) || !Equal(test.got, test.want) {
+			t.Errorf("%s: got %T %v, want %T %v", test.name, test.got, test.got, test.want, test.want)
+		}
+	}
+}
+
+func TestDivisions(t *testing.T) {
+	tests := []struct {
+		name string
+		op   func(a, b any) (any, error)
+		a, b any
+		want any
+	}{
+		{"exact", Divide, int64(6), int64(3), int64(2)},
+		{"inexact", Divide, int64(7), int64(2), 3.5},
+		{"most negative by -1", Divide, int64(math.MinInt64), int64(-1), bigInt("9223372036854775808")},
+		{"big inexact", Divide, bigInt("100000000000000000000000000001"), int64(10), 1e28},
+		{"float", Divide, 1.0, int64(4), 0.25},
+		{"floor", FloorDivide, int64(7), int64(2), int64(3)},
+		{"floor negative", FloorDivide, int64(-7), int64(2), int64(-4)},
+		{"floor float", FloorDivide, 7.5, int64(2), 3.0},
+		{"modulo", Modulo, int64(7), int64(3), int64(1)},
+		{"modulo negative dividend", Modulo, int64(-7), int64(3), int64(2)},
+		{"modulo negative divisor", Modulo, int64(7), int64(-3), int64(-2)},
+		{"modulo float", Modulo, -5.5, int64(2), 0.5},
+	}
+
+	for _, test := range tests {
+		got, err := test.op(test.a, test.b)
+		if err != nil || reflect.TypeOf(got) != reflect.TypeOf(test.want) || !Equal(got, test.want) {
+			t.Errorf("%s: got %T %v (%v), want %T %v", test.name, got, got, err, test.want, test.want)
+		}
+	}
+
+	for _, divisor := range []any{int64(0), new(big.Int), 0.0} {
+		if _, err := Divide(int64(1), divisor); err != ErrDivisionByZero {
+			t.Errorf("1 / %v: got error %v, want %v", divisor, err, ErrDivisionByZero)
+		}
+	}
+}
+
+func TestPowerLimits(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b any
+		want any
+	}{
+		{"one to a huge power", int64(1), bigInt("100000000000000000000"), int64(1)},
+		{"minus one to a huge power", int64(-1), bigInt("100000000000000000001"), int64(-1)},
+		{"two past the limit", int64(2), int64(MaxBits), ErrTooLarge},
+		{"three past the limit", int64(3), int64(MaxBits * 2 / 3), ErrTooLarge},
+		{"huge exponent", int64(2), int64(10000000000), ErrTooLarge},
+		{"decimal scale past the limit", decimal("0.5"), int64(MaxScale + 1), ErrDecimalScale},
+		{"decimal scale overflow", decimal("0.01"), int64(math.MaxInt64), ErrDecimalScale},
+		{"decimal coefficient past the limit", decimal("2"), int64(MaxBits), ErrTooLarge},
+	}
+
+	for _, test := range tests {
+		got := result(Power(test.a, test.b))
+		if err, ok := test.want.(error); ok {
+			if got != err {
+				t.Errorf("%s: got %v, want %v", test.name, got, err)
+			}
+		} else if reflect.TypeOf(got) != reflect.TypeOf(test.want) || !Equal(got, test.want) {
+			t.Errorf("%s: got %T %v, want %T %v", test.name, got, got, test.want, test.want)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b    any
+		want    int
+		ordered bool
+	}{
+		{int64(1), 1.0, 0, true},
+		{int64(1), int64(2), -1, true},
+		{bigInt("9223372036854775808"), int64(math.MaxInt64), 1, true},
+		// the int64 nearest 2^63 as a float, equal to it only when rounded
+		{int64(math.MaxInt64), 9223372036854775808.0, -1, true},
+		{math.Inf(1), bigInt("100000000000000000000000000000"), 1, true},
+		{math.NaN(), int64(1), 0, false},
+	}
+
+	for _, test := range tests {
+		got, ordered := Compare(test.a, test.b)
+		if got != test.want || ordered != test.ordered {
+			t.Errorf("Compare(%v, %v) = %d, %t, want %d, %t", test.a, test.b, got, ordered, test.want, test.ordered)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		value any
+		want  string
+	}{
+		{int64(-42), "-42"},
+		{bigInt("123456789012345678901234567890"), "123456789012345678901234567890"},
+		{0.1, "0.1"},
+		{2.0, "2"},
+		{tenth + 0.2, "0.30000000000000004"},
+		{1e21, "1e+21"},
+		{1e-7, "1e-07"},
+		{math.Inf(-1), "-Infinity"},
+		{math.NaN(), "NaN"},
+	}
+
+	for _, test := range tests {
+		if got := Format(test.value); got != test.want {
+			t.Errorf("Format(%v) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
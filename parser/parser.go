@@ -2,17 +2,23 @@ package parser
 
 import (
 	"fmt"
+	"golox/errors"
 	"golox/expr"
 	"golox/stmt"
 	"golox/token"
 	"slices"
+	"strings"
+	"unicode/utf8"
 )
 
-// program        -> declaration* EOF ;
+// program        -> ( testDecl | benchDecl | declaration )* EOF ;
+// testDecl       -> "test" STRING block ;
+// benchDecl      -> "bench" STRING block ;
 // declaration    -> varDecl
 //                | statement ;
 // varDecl        -> "var" IDENTIFY ( "=" expression )? ";" ;
 // statement      → exprStmt
+//                | assertStmt
 //                | forStmt
 //                | ifStmt
 //                | printStmt
@@ -21,6 +27,8 @@ import (
 //                | block ;
 
 // exprStmt       → expression ";" ;
+// assertStmt     → "assert" expression ";" ;
+//                  assert is an ordinary identifier unless an operand follows it
 // forStmt        → "for" "(" ( varDecl | exprStmt | ";" )
 //                            expression? ";"
 //                            expression? ")" statement ;
@@ -35,21 +43,35 @@ import (
 
 // expression     → assignment ;
 
-// assignment     → ( call "." )? IDENTIFIER "=" assignment
-//                | logic_or ;
+// assignment     → ( call "." )? IDENTIFIER ( "=" | "+=" | "-=" | "*=" | "/=" | "%=" ) assignment
+//                | conditional ;
 //
+// conditional    → coalesce ( "?" expression ":" conditional )? ;
+// coalesce       → logic_or ( "??" logic_or )* ;
 // logic_or       → logic_and ( "or" logic_and )* ;
 // logic_and      → equality ( "and" equality )* ;
 // equality       → comparison ( ( "!=" | "==" ) comparison )* ;
-// comparison     → term ( ( ">" | ">=" | "<" | "<=" ) term )* ;
+// comparison     → bitOr ( ( ">" | ">=" | "<" | "<=" ) bitOr )* ;
+// bitOr          → bitXor ( "|" bitXor )* ;
+// bitXor         → bitAnd ( "^" bitAnd )* ;
+// bitAnd         → shift ( "&" shift )* ;
+// shift          → term ( ( "<<" | ">>" ) term )* ;
 // term           → factor ( ( "-" | "+" ) factor )* ;
-// factor         → unary ( ( "/" | "*" ) unary )* ;
+// factor         → unary ( ( "/" | "*" | "%" | "~/" ) unary )* ;
 //
-// unary          → ( "!" | "-" ) unary | call ;
+// unary          → ( "!" | "-" | "~" ) unary | power ;
+// power          → update ( "**" unary )? ;
+// update         → ( "++" | "--" ) call | call ( "++" | "--" )? ;
 // call           → primary ( "(" arguments? ")" )* ;
+//
+// "**" binds tighter than the unary operators on its left and is right-associative,
+// -2 ** 2 is -(2 ** 2) and 2 ** 3 ** 2 is 2 ** (3 ** 2). Its right operand can be
+// a unary expression, 2 ** -1 is 0.5.
 // primary        → "true" | "false" | "nil" | "this"
 //                | NUMBER | STRING | IDENTIFIER | "(" expression ")"
+//                | interpolation
 //                | "super" "." IDENTIFIER ;
+// interpolation  → INTERPOLATION expression ( INTERPOLATION expression )* STRING ;
 
 type parseError struct {
 	message string
@@ -63,10 +85,37 @@ func (p parseError) Error() string {
 	return fmt.Sprintf("[line %d] ParseError at '%s': %s", p.token.Line, p.token.Lexeme, p.message)
 }
 
+// Diagnostic converts an error returned by Parse to a diagnostic located at the offending token.
+func Diagnostic(err error) (errors.Diagnostic, bool) {
+	pe, ok := err.(parseError)
+	if !ok {
+		return errors.Diagnostic{}, false
+	}
+
+	where := pe.token.Lexeme
+	if pe.token.TokenType == token.EOF {
+		where = "end"
+	}
+
+	return errors.Diagnostic{
+		Severity: errors.SeverityError,
+		Span: errors.Span{
+			Line:      pe.token.Line,
+			Column:    pe.token.Column,
+			EndLine:   pe.token.Line,
+			EndColumn: pe.token.Column + utf8.RuneCountInString(pe.token.Lexeme),
+		},
+		Where:   where,
+		Message: pe.message,
+	}, true
+}
+
 type Parser struct {
 	Tokens  []token.Token
 	current int
 	errors  []error
+	// number of blocks being parsed, tests can only be declared outside of them
+	blocks int
 }
 
 func NewParser(tokens []token.Token) *Parser {
@@ -95,6 +144,25 @@ func (p *Parser) Parse() (stmts []stmt.Statement[any], err []error) {
 	return statements, nil
 }
 
+// ParseExpression parses tokens holding a single expression, such as one typed in the debugger.
+func (p *Parser) ParseExpression() (exp expr.Expression[any], err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pe, ok := r.(parseError)
+			if !ok {
+				panic(r)
+			}
+			exp, err = nil, pe
+		}
+	}()
+
+	exp = p.expression()
+	if !p.isAtEnd() {
+		panic(p.error(p.peek(), "Expected end of expression."))
+	}
+	return exp, nil
+}
+
 func (p *Parser) declaration() stmt.Statement[any] {
 	defer func() {
 		if r := recover(); r != nil {
@@ -112,9 +180,31 @@ func (p *Parser) declaration() stmt.Statement[any] {
 	if p.match(token.VAR) {
 		return p.varDecleration()
 	}
+	if p.checkContextual("test") {
+		keyword, name, body := p.namedBlock("test")
+		return stmt.NewTestStmt(keyword, name, body)
+	}
+	if p.checkContextual("bench") {
+		keyword, name, body := p.namedBlock("benchmark")
+		return stmt.NewBenchStmt(keyword, name, body)
+	}
 	return p.statement()
 }
 
+// namedBlock parses a test or benchmark declaration, its word is an ordinary identifier unless a name follows it.
+func (p *Parser) namedBlock(kind string) (token.Token, string, *stmt.BlockStmt[any]) {
+	keyword := p.advance()
+	if p.blocks > 0 {
+		// the declaration still parses, there is no need to synchronize
+		message := fmt.Sprintf("%ss must be declared at the top level.", strings.ToUpper(kind[:1])+kind[1:])
+		p.errors = append(p.errors, p.error(keyword, message))
+	}
+
+	name := p.consume(token.STRING, fmt.Sprintf("Expected the %s name.", kind))
+	p.consume(token.LEFT_BRACE, fmt.Sprintf("Expected '{' before the %s body.", kind))
+	return keyword, name.Literal.(string), stmt.NewBlockStmt(p.block())
+}
+
 func (p *Parser) varDecleration() stmt.Statement[any] {
 	name := p.consume(token.IDENTIFIER, "Expected a variable name.")
 
@@ -131,6 +221,10 @@ func (p *Parser) statement() stmt.Statement[any] {
 	if p.match(token.PRINT) {
 		return p.printStatement()
 	}
+	if p.checkAssert() {
+		p.advance()
+		return p.assertStatement()
+	}
 	if p.match(token.LEFT_BRACE) {
 		return stmt.NewBlockStmt(p.block())
 	}
@@ -148,6 +242,7 @@ func (p *Parser) statement() stmt.Statement[any] {
 }
 
 func (p *Parser) forStmt() stmt.Statement[any] {
+	keyword := p.previous()
 	p.consume(token.LEFT_PAREN, "Expect '(' after the for keyword.")
 
 	var initializer stmt.Statement[any]
@@ -180,7 +275,7 @@ func (p *Parser) forStmt() stmt.Statement[any] {
 	if condition == nil {
 		condition = expr.NewLiteral[any](true)
 	}
-	body = stmt.NewWhileStmt(condition, body)
+	body = stmt.NewWhileStmt(keyword, condition, body)
 
 	if initializer != nil {
 		body = stmt.NewBlockStmt([]stmt.Statement[any]{initializer, body})
@@ -190,16 +285,18 @@ func (p *Parser) forStmt() stmt.Statement[any] {
 }
 
 func (p *Parser) whileStmt() stmt.Statement[any] {
+	keyword := p.previous()
 	p.consume(token.LEFT_PAREN, "Expect '(' after the while keyword.")
 	condition := p.expression()
 	p.consume(token.RIGHT_PAREN, "Expect ')' after the condition.")
 	body := p.statement()
 
-	return stmt.NewWhileStmt(condition, body)
+	return stmt.NewWhileStmt(keyword, condition, body)
 }
 
 // parsing the if statement.
 func (p *Parser) ifStatement() stmt.Statement[any] {
+	keyword := p.previous()
 	p.consume(token.LEFT_PAREN, "Expect '(' after the if statement.")
 	condition := p.expression()
 	p.consume(token.RIGHT_PAREN, "Expect ')' at the end of the if statement.")
@@ -210,12 +307,15 @@ func (p *Parser) ifStatement() stmt.Statement[any] {
 		elseBranch = p.statement()
 	}
 
-	return stmt.NewIfStmt(condition, thenBranch, elseBranch)
+	return stmt.NewIfStmt(keyword, condition, thenBranch, elseBranch)
 }
 
 func (p *Parser) block() []stmt.Statement[any] {
 	var stmts []stmt.Statement[any]
 
+	p.blocks++
+	defer func() { p.blocks-- }()
+
 	for !p.check(token.RIGHT_BRACE) && !p.isAtEnd() {
 		stmts = append(stmts, p.declaration())
 	}
@@ -225,9 +325,34 @@ func (p *Parser) block() []stmt.Statement[any] {
 }
 
 func (p *Parser) printStatement() stmt.Statement[any] {
+	keyword := p.previous()
 	exp := p.expression()
 	p.consume(token.SEMICOLON, "Expected ';' after value.")
-	return stmt.NewPrintStmt(exp)
+	return stmt.NewPrintStmt(keyword, exp)
+}
+
+func (p *Parser) assertStatement() stmt.Statement[any] {
+	keyword := p.previous()
+	start := p.current
+	exp := p.expression()
+	source := sourceText(p.Tokens[start:p.current])
+	p.consume(token.SEMICOLON, "Expected ';' after assertion.")
+	return stmt.NewAssertStmt(keyword, exp, source)
+}
+
+// sourceText rebuilds the source of consecutive tokens, any gap between two tokens becomes a single space.
+func sourceText(tokens []token.Token) string {
+	var builder strings.Builder
+	for i, tok := range tokens {
+		if i > 0 {
+			previous := tokens[i-1]
+			if tok.Line != previous.Line || tok.Column != previous.Column+utf8.RuneCountInString(previous.Lexeme) {
+				builder.WriteString(" ")
+			}
+		}
+		builder.WriteString(tok.Lexeme)
+	}
+	return builder.String()
 }
 
 func (p *Parser) expressionStatement() stmt.Statement[any] {
@@ -242,14 +367,17 @@ func (p *Parser) expression() expr.Expression[any] {
 
 func (p *Parser) assignment() expr.Expression[any] {
 	// exp := p.equality()
-	exp := p.or()
+	exp := p.conditional()
 
-	if p.match(token.EQUAL) {
+	if p.match(token.EQUAL, token.PLUS_EQUAL, token.MINUS_EQUAL, token.STAR_EQUAL, token.SLASH_EQUAL, token.PERCENT_EQUAL) {
 		equals := p.previous()
 		value := p.assignment()
 
 		if variable, ok := exp.(*expr.Variable[any]); ok {
 			name := variable.Name
+			if equals.TokenType != token.EQUAL {
+				return expr.NewCompoundAssignment(name, equals, value, false)
+			}
 			return expr.NewAssignment(name, value)
 		}
 
@@ -259,6 +387,34 @@ func (p *Parser) assignment() expr.Expression[any] {
 	return exp
 }
 
+func (p *Parser) conditional() expr.Expression[any] {
+	exp := p.coalesce()
+
+	if p.match(token.QUESTION) {
+		question := p.previous()
+		then := p.expression()
+		p.consume(token.COLON, "Expected ':' after the then branch of a conditional expression.")
+		// the else branch parses the next "?" itself, making the operator right-associative
+		otherwise := p.conditional()
+		exp = expr.NewConditional(exp, question, then, otherwise)
+	}
+
+	return exp
+}
+
+// coalesce parses a ?? b, which evaluates b only when a is nil.
+func (p *Parser) coalesce() expr.Expression[any] {
+	exp := p.or()
+
+	for p.match(token.QUESTION_QUESTION) {
+		operator := p.previous()
+		right := p.or()
+		exp = expr.NewLogical(exp, operator, right)
+	}
+
+	return exp
+}
+
 func (p *Parser) or() expr.Expression[any] {
 	exp := p.and()
 	for p.match(token.OR) {
@@ -295,9 +451,57 @@ func (p *Parser) equality() expr.Expression[any] {
 }
 
 func (p *Parser) comparasion() expr.Expression[any] {
-	expression := p.term()
+	expression := p.bitOr()
 
 	for p.match(token.GREATER, token.GREATER_EQUAL, token.LESS, token.LESS_EQUAL) {
+		operator := p.previous()
+		right := p.bitOr()
+		expression = expr.NewBinary(expression, operator, right)
+	}
+
+	return expression
+}
+
+func (p *Parser) bitOr() expr.Expression[any] {
+	expression := p.bitXor()
+
+	for p.match(token.PIPE) {
+		operator := p.previous()
+		right := p.bitXor()
+		expression = expr.NewBinary(expression, operator, right)
+	}
+
+	return expression
+}
+
+func (p *Parser) bitXor() expr.Expression[any] {
+	expression := p.bitAnd()
+
+	for p.match(token.CARET) {
+		operator := p.previous()
+		right := p.bitAnd()
+		expression = expr.NewBinary(expression, operator, right)
+	}
+
+	return expression
+}
+
+func (p *Parser) bitAnd() expr.Expression[any] {
+	expression := p.shift()
+
+	for p.match(token.AMPERSAND) {
+		operator := p.previous()
+		right := p.shift()
+		expression = expr.NewBinary(expression, operator, right)
+	}
+
+	return expression
+}
+
+func (p *Parser) shift() expr.Expression[any] {
+	expression := p.term()
+
+	for p.match(token.LESS_LESS, token.GREATER_GREATER) {
 		operator := p.previous()
 		right := p.term()
 		expression = expr.NewBinary(expression, operator, right)
@@ -321,7 +525,7 @@ func (p *Parser) term() expr.Expression[any] {
 func (p *Parser) factor() expr.Expression[any] {
 	expression := p.unary()
 
-	for p.match(token.SLASH, token.STAR) {
+	for p.match(token.SLASH, token.STAR, token.PERCENT, token.TILDE_SLASH) {
 		operator := p.previous()
 		right := p.unary()
 		expression = expr.NewBinary(expression, operator, right)
@@ -331,13 +535,48 @@ func (p *Parser) factor() expr.Expression[any] {
 }
 
 func (p *Parser) unary() expr.Expression[any] {
-	for p.match(token.BANG, token.MINUS) {
+	for p.match(token.BANG, token.MINUS, token.TILDE) {
 		operator := p.previous()
 		right := p.unary()
 		return expr.NewUnary(operator, right)
 	}
 
-	return p.call()
+	return p.power()
+}
+
+func (p *Parser) power() expr.Expression[any] {
+	expression := p.update()
+
+	// the right operand parses the next "**" itself, making the operator right-associative
+	if p.match(token.STAR_STAR) {
+		operator := p.previous()
+		right := p.unary()
+		expression = expr.NewBinary(expression, operator, right)
+	}
+
+	return expression
+}
+
+// update parses the increments and decrements, which can only apply to variables.
+func (p *Parser) update() expr.Expression[any] {
+	if p.match(token.PLUS_PLUS, token.MINUS_MINUS) {
+		operator := p.previous()
+		target := p.call()
+		return p.updateTarget(target, operator, false)
+	}
+
+	expression := p.call()
+	if p.match(token.PLUS_PLUS, token.MINUS_MINUS) {
+		return p.updateTarget(expression, p.previous(), true)
+	}
+	return expression
+}
+
+func (p *Parser) updateTarget(target expr.Expression[any], operator token.Token, postfix bool) expr.Expression[any] {
+	if variable, ok := target.(*expr.Variable[any]); ok {
+		return expr.NewCompoundAssignment[any](variable.Name, operator, nil, postfix)
+	}
+	panic(p.error(operator, "Invalid assignment target!"))
 }
 
 func (p *Parser) call() expr.Expression[any] {
@@ -384,6 +623,8 @@ func (p *Parser) primary() expr.Expression[any] {
 	case p.match(token.STRING, token.NUMBER):
 		tok := p.previous()
 		return expr.NewLiteral[any](tok.Literal)
+	case p.match(token.INTERPOLATION):
+		return p.interpolation()
 	case p.match(token.LEFT_PAREN):
 		expression := p.expression()
 		p.consume(token.RIGHT_PAREN, "Expected ')' after expression")
@@ -395,6 +636,44 @@ func (p *Parser) primary() expr.Expression[any] {
 	panic(p.error(p.peek(), "Expected expression"))
 }
 
+// interpolation parses the rest of a string with embedded expressions, its first part is the previous token.
+func (p *Parser) interpolation() expr.Expression[any] {
+	start := p.previous()
+
+	var parts []expr.Expression[any]
+	text := start.Literal.(string)
+	for {
+		if text != "" {
+			parts = append(parts, expr.NewLiteral[any](text))
+		}
+		if p.continuesString() {
+			panic(p.error(p.peek(), "Expected an expression inside '${}'."))
+		}
+		parts = append(parts, p.expression())
+
+		if !p.match(token.INTERPOLATION) {
+			break
+		}
+		text = p.previous().Literal.(string)
+	}
+
+	if !p.continuesString() {
+		panic(p.error(p.peek(), "Expected '}' after the interpolated expression."))
+	}
+	end := p.advance()
+	if text := end.Literal.(string); text != "" {
+		parts = append(parts, expr.NewLiteral[any](text))
+	}
+
+	return expr.NewInterpolation(start, parts, end)
+}
+
+// continuesString reports whether the next token is the part of an interpolated string following
+// an expression, it starts with the '}' closing the expression unlike a string of its own.
+func (p *Parser) continuesString() bool {
+	return (p.check(token.STRING) || p.check(token.INTERPOLATION)) && strings.HasPrefix(p.peek().Lexeme, "}")
+}
+
 // Utility functions
 func (p *Parser) consume(tokenType token.TokenType, message string) token.Token {
 	if p.check(tokenType) {
@@ -438,6 +717,29 @@ func (p *Parser) match(tokens ...token.TokenType) bool {
 	return false
 }
 
+// checkContextual reports whether the next token is the identifier word introducing a declaration,
+// which it only does when a STRING follows it.
+func (p *Parser) checkContextual(word string) bool {
+	if !p.check(token.IDENTIFIER) || p.peek().Lexeme != word || p.current+1 >= len(p.Tokens) {
+		return false
+	}
+	return p.Tokens[p.current+1].TokenType == token.STRING
+}
+
+// checkAssert reports whether the next token is the identifier assert starting an assertion,
+// which it only does when an operand follows it, assert = 1; and assert++; use a variable.
+func (p *Parser) checkAssert() bool {
+	if !p.check(token.IDENTIFIER) || p.peek().Lexeme != "assert" || p.current+1 >= len(p.Tokens) {
+		return false
+	}
+	switch p.Tokens[p.current+1].TokenType {
+	case token.IDENTIFIER, token.NUMBER, token.STRING, token.INTERPOLATION, token.TRUE, token.FALSE,
+		token.NIL, token.THIS, token.SUPER, token.LEFT_PAREN, token.BANG, token.MINUS, token.TILDE:
+		return true
+	}
+	return false
+}
+
 func (p *Parser) check(tokType token.TokenType) bool {
 	if p.isAtEnd() {
 		return false
@@ -0,0 +1,179 @@
+package profiler
+
+import (
+	"compress/gzip"
+	"io"
+	"slices"
+)
+
+// WriteProfile writes the samples in the gzipped protocol buffer format read by go tool pprof.
+// Every Lox line is a location of its function, so the call stacks show as Lox frames.
+func (p *Profiler) WriteProfile(w io.Writer) error {
+	b := &pprofBuilder{strings: map[string]int{"": 0}, table: []string{""}, functions: map[string]uint64{}, locations: map[Line]uint64{}}
+
+	// sample types: how many times the stack was seen and the self time spent there
+	b.message(1, func(m *protobuf) {
+		m.int64(1, b.string("count"))
+		m.int64(2, b.string("count"))
+	})
+	b.message(1, func(m *protobuf) {
+		m.int64(1, b.string("time"))
+		m.int64(2, b.string("nanoseconds"))
+	})
+
+	keys := make([]string, 0, len(p.samples))
+	for key := range p.samples {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		s := p.samples[key]
+		var locations []uint64
+		for _, line := range s.stack {
+			locations = append(locations, b.location(line, p.path))
+		}
+
+		b.message(2, func(m *protobuf) {
+			m.packedUint64(1, locations)
+			m.packedInt64(2, []int64{s.count, int64(s.self)})
+		})
+	}
+
+	b.data = append(b.data, b.locationData...)
+	b.data = append(b.data, b.functionData...)
+	for _, str := range b.table {
+		b.bytes(6, []byte(str))
+	}
+
+	b.int64(9, p.start.UnixNano())
+	b.int64(10, int64(p.duration))
+	b.message(11, func(m *protobuf) {
+		m.int64(1, b.string("time"))
+		m.int64(2, b.string("nanoseconds"))
+	})
+
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(b.data); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// pprofBuilder collects the string, function and location tables of a profile.
+type pprofBuilder struct {
+	protobuf
+	strings      map[string]int
+	table        []string
+	functions    map[string]uint64
+	locations    map[Line]uint64
+	functionData []byte
+	locationData []byte
+}
+
+func (b *pprofBuilder) string(s string) int64 {
+	index, ok := b.strings[s]
+	if !ok {
+		index = len(b.table)
+		b.strings[s] = index
+		b.table = append(b.table, s)
+	}
+	return int64(index)
+}
+
+func (b *pprofBuilder) function(name, path string) uint64 {
+	// pprof strips what is between angle brackets from names, as it does with C++ templates
+	if name == scriptFunction {
+		name = "main"
+	}
+
+	id, ok := b.functions[name]
+	if !ok {
+		id = uint64(len(b.functions) + 1)
+		b.functions[name] = id
+
+		m := &protobuf{}
+		m.uint64(1, id)
+		m.int64(2, b.string(name))
+		m.int64(3, b.string(name))
+		m.int64(4, b.string(path))
+		f := &protobuf{}
+		f.bytes(5, m.data)
+		b.functionData = append(b.functionData, f.data...)
+	}
+	return id
+}
+
+func (b *pprofBuilder) location(line Line, path string) uint64 {
+	id, ok := b.locations[line]
+	if !ok {
+		id = uint64(len(b.locations) + 1)
+		b.locations[line] = id
+
+		function := b.function(line.Function, path)
+		m := &protobuf{}
+		m.uint64(1, id)
+		m.message(4, func(l *protobuf) {
+			l.uint64(1, function)
+			l.int64(2, int64(line.Line))
+		})
+		l := &protobuf{}
+		l.bytes(4, m.data)
+		b.locationData = append(b.locationData, l.data...)
+	}
+	return id
+}
+
+// protobuf encodes the few wire types a profile needs.
+type protobuf struct {
+	data []byte
+}
+
+func (p *protobuf) varint(x uint64) {
+	for x >= 0x80 {
+		p.data = append(p.data, byte(x)|0x80)
+		x >>= 7
+	}
+	p.data = append(p.data, byte(x))
+}
+
+func (p *protobuf) key(field, wireType int) {
+	p.varint(uint64(field<<3 | wireType))
+}
+
+func (p *protobuf) uint64(field int, x uint64) {
+	p.key(field, 0)
+	p.varint(x)
+}
+
+func (p *protobuf) int64(field int, x int64) {
+	p.uint64(field, uint64(x))
+}
+
+func (p *protobuf) bytes(field int, data []byte) {
+	p.key(field, 2)
+	p.varint(uint64(len(data)))
+	p.data = append(p.data, data...)
+}
+
+func (p *protobuf) message(field int, encode func(m *protobuf)) {
+	m := &protobuf{}
+	encode(m)
+	p.bytes(field, m.data)
+}
+
+func (p *protobuf) packedUint64(field int, xs []uint64) {
+	m := &protobuf{}
+	for _, x := range xs {
+		m.varint(x)
+	}
+	p.bytes(field, m.data)
+}
+
+func (p *protobuf) packedInt64(field int, xs []int64) {
+	m := &protobuf{}
+	for _, x := range xs {
+		m.varint(uint64(x))
+	}
+	p.bytes(field, m.data)
+}
@@ -0,0 +1,246 @@
+// Package profiler times the statements and calls of a program, for --profile.
+package profiler
+
+import (
+	"fmt"
+	"golox/interpreter"
+	"golox/stmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// the function the script's own statements run in
+const scriptFunction = "<script>"
+
+// Stats are the timings of a line or a function. Self time leaves out the time
+// spent in nested statements and calls, total time includes it.
+type Stats struct {
+	Count int
+	Self  time.Duration
+	Total time.Duration
+}
+
+// Line is a source line of a function.
+type Line struct {
+	Function string
+	Line     int
+}
+
+// activity is a statement or a call being timed.
+type activity struct {
+	line     Line
+	isCall   bool
+	start    time.Time
+	children time.Duration
+}
+
+// Profiler is an interpreter hook instrumenting every statement and call.
+type Profiler struct {
+	path string
+
+	Lines     map[Line]*Stats
+	Functions map[string]*Stats
+
+	activities []activity
+	// how many times a line or function is on the stack, recursion counts once in the total
+	activeLines     map[Line]int
+	activeFunctions map[string]int
+	// the functions being called, the call site lines are kept in the activities
+	functions []string
+	samples   map[string]*sample
+	starts    map[stmt.Statement[any]]int
+
+	start    time.Time
+	duration time.Duration
+}
+
+// sample is the time spent with a given stack, the innermost line first.
+type sample struct {
+	stack []Line
+	count int64
+	self  time.Duration
+}
+
+// New returns a profiler for the script at path, the path names the source file in the pprof output.
+func New(path string) *Profiler {
+	return &Profiler{
+		path:            path,
+		Lines:           map[Line]*Stats{},
+		Functions:       map[string]*Stats{},
+		activeLines:     map[Line]int{},
+		activeFunctions: map[string]int{},
+		functions:       []string{scriptFunction},
+		samples:         map[string]*sample{},
+		starts:          map[stmt.Statement[any]]int{},
+	}
+}
+
+// Start starts the clock of the script, Stop stops it.
+func (p *Profiler) Start() {
+	p.start = time.Now()
+}
+
+func (p *Profiler) Stop() {
+	p.duration = time.Since(p.start)
+
+	script := p.function(scriptFunction)
+	script.Count, script.Total = 1, p.duration
+}
+
+func (p *Profiler) BeforeExecute(i *interpreter.Interpreter, st stmt.Statement[any]) {
+	line, ok := p.starts[st]
+	if !ok {
+		line, _ = stmt.Lines(st)
+		p.starts[st] = line
+	}
+
+	key := Line{Function: p.functions[len(p.functions)-1], Line: line}
+	p.activeLines[key]++
+	p.activities = append(p.activities, activity{line: key, start: time.Now()})
+}
+
+func (p *Profiler) AfterExecute(i *interpreter.Interpreter, st stmt.Statement[any]) {
+	a, self, elapsed := p.finish()
+
+	stats := p.line(a.line)
+	stats.Count++
+	stats.Self += self
+	if p.activeLines[a.line]--; p.activeLines[a.line] == 0 {
+		stats.Total += elapsed
+	}
+
+	p.function(a.line.Function).Self += self
+	p.sample(self, a.line)
+}
+
+func (p *Profiler) BeforeCall(i *interpreter.Interpreter, call interpreter.Call) {
+	p.activeFunctions[call.Name]++
+	p.activities = append(p.activities, activity{
+		line:   Line{Function: p.functions[len(p.functions)-1], Line: call.Paren.Line},
+		isCall: true,
+		start:  time.Now(),
+	})
+	p.functions = append(p.functions, call.Name)
+}
+
+func (p *Profiler) AfterCall(i *interpreter.Interpreter, call interpreter.Call) {
+	a, self, elapsed := p.finish()
+	p.functions = p.functions[:len(p.functions)-1]
+	stats := p.function(call.Name)
+	stats.Count++
+	stats.Self += self
+	if p.activeFunctions[call.Name]--; p.activeFunctions[call.Name] == 0 {
+		stats.Total += elapsed
+	}
+
+	// the time of the call itself, a native function for instance, has no line of its own
+	p.sample(self, Line{Function: call.Name}, a.line)
+}
+
+// finish pops the innermost activity and charges its time to the enclosing one.
+func (p *Profiler) finish() (a activity, self, elapsed time.Duration) {
+	a = p.activities[len(p.activities)-1]
+	p.activities = p.activities[:len(p.activities)-1]
+
+	elapsed = time.Since(a.start)
+	if n := len(p.activities); n > 0 {
+		p.activities[n-1].children += elapsed
+	}
+	return a, elapsed - a.children, elapsed
+}
+
+// sample adds self time to the current stack, below the innermost lines given.
+func (p *Profiler) sample(self time.Duration, innermost ...Line) {
+	stack := slices.Clone(innermost)
+	for j := len(p.activities) - 1; j >= 0; j-- {
+		if p.activities[j].isCall {
+			stack = append(stack, p.activities[j].line)
+		}
+	}
+
+	var key strings.Builder
+	for _, line := range stack {
+		fmt.Fprintf(&key, "%s:%d;", line.Function, line.Line)
+	}
+
+	s, ok := p.samples[key.String()]
+	if !ok {
+		s = &sample{stack: stack}
+		p.samples[key.String()] = s
+	}
+	s.count++
+	s.self += self
+}
+
+func (p *Profiler) line(key Line) *Stats {
+	stats, ok := p.Lines[key]
+	if !ok {
+		stats = &Stats{}
+		p.Lines[key] = stats
+	}
+	return stats
+}
+
+func (p *Profiler) function(name string) *Stats {
+	stats, ok := p.Functions[name]
+	if !ok {
+		stats = &Stats{}
+		p.Functions[name] = stats
+	}
+	return stats
+}
+
+// WriteSummary writes the n lines and functions with the most self time.
+func (p *Profiler) WriteSummary(w io.Writer, n int) {
+	file := filepath.Base(p.path)
+
+	lines := make([]Line, 0, len(p.Lines))
+	for line := range p.Lines {
+		lines = append(lines, line)
+	}
+	slices.SortFunc(lines, func(a, b Line) int {
+		if c := compareStats(p.Lines[a], p.Lines[b]); c != 0 {
+			return c
+		}
+		return a.Line - b.Line
+	})
+
+	fmt.Fprintf(w, "Total time %v\n\nTop %d lines by self time:\n", p.duration, n)
+	fmt.Fprintf(w, "%12s %12s %10s  %s\n", "self", "total", "count", "line")
+	for _, line := range lines[:min(n, len(lines))] {
+		stats := p.Lines[line]
+		fmt.Fprintf(w, "%12v %12v %10d  %s:%d (%s)\n", stats.Self, stats.Total, stats.Count, file, line.Line, line.Function)
+	}
+
+	functions := make([]string, 0, len(p.Functions))
+	for name := range p.Functions {
+		functions = append(functions, name)
+	}
+	slices.SortFunc(functions, func(a, b string) int {
+		if c := compareStats(p.Functions[a], p.Functions[b]); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+
+	fmt.Fprintf(w, "\nTop %d functions by self time:\n", n)
+	fmt.Fprintf(w, "%12s %12s %10s  %s\n", "self", "total", "calls", "function")
+	for _, name := range functions[:min(n, len(functions))] {
+		stats := p.Functions[name]
+		fmt.Fprintf(w, "%12v %12v %10d  %s\n", stats.Self, stats.Total, stats.Count, name)
+	}
+}
+
+// compareStats orders by decreasing self time.
+func compareStats(a, b *Stats) int {
+	switch {
+	case a.Self > b.Self:
+		return -1
+	case a.Self < b.Self:
+		return 1
+	}
+	return 0
+}
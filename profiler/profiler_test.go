@@ -0,0 +1,99 @@
+package profiler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"golox/interpreter"
+	"golox/parser"
+	"golox/scanner"
+	"io"
+	"strings"
+	"testing"
+)
+
+func profile(t *testing.T, source string) *Profiler {
+	t.Helper()
+
+	statements, errs := parser.NewParser(scanner.NewScanner(source).ScanTokens()).Parse()
+	if errs != nil {
+		t.Fatalf("parse errors: %v", errs)
+	}
+
+	p := New("test.lox")
+	interp := interpreter.NewInterpreter()
+	interp.Out = io.Discard
+	interp.Hook = p
+
+	p.Start()
+	interp.Interpret(statements)
+	p.Stop()
+	return p
+}
+
+const program = `var n = 0;
+while (n < 10) {
+  n = n + 1;
+  clock();
+}
+`
+
+func TestCounts(t *testing.T) {
+	p := profile(t, program)
+
+	// the block of the loop body starts on line 3 too
+	want := map[int]int{1: 1, 2: 1, 3: 20, 4: 10}
+	for line, count := range want {
+		stats := p.Lines[Line{Function: scriptFunction, Line: line}]
+		if stats == nil || stats.Count != count {
+			t.Errorf("line %d: got %+v, want %d executions", line, stats, count)
+			continue
+		}
+		if stats.Total < stats.Self {
+			t.Errorf("line %d: total %v below self %v", line, stats.Total, stats.Self)
+		}
+	}
+
+	if clock := p.Functions["clock"]; clock == nil || clock.Count != 10 {
+		t.Errorf("got clock %+v, want 10 calls", clock)
+	}
+	if script := p.Functions[scriptFunction]; script.Total < script.Self || script.Total < p.Lines[Line{Function: scriptFunction, Line: 2}].Total {
+		t.Errorf("got script %+v", script)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	var summary strings.Builder
+	profile(t, program).WriteSummary(&summary, 2)
+
+	lines := strings.Split(summary.String(), "\n")
+	if !strings.HasPrefix(lines[0], "Total time") || lines[2] != "Top 2 lines by self time:" || !strings.Contains(summary.String(), "Top 2 functions by self time:") {
+		t.Errorf("got summary:\n%s", summary.String())
+	}
+	// the header and two lines only
+	if lines[6] != "" || !strings.HasPrefix(lines[7], "Top 2 functions") {
+		t.Errorf("got summary:\n%s", summary.String())
+	}
+}
+
+func TestWriteProfile(t *testing.T) {
+	var out bytes.Buffer
+	if err := profile(t, program).WriteProfile(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	gz, err := gzip.NewReader(&out)
+	if err != nil {
+		t.Fatalf("profile isn't gzipped: %v", err)
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the string table names the Lox frames and the source file
+	for _, name := range []string{"main", "clock", "test.lox", "nanoseconds"} {
+		if !bytes.Contains(data, []byte(name)) {
+			t.Errorf("profile misses %q", name)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package resolver
+
+import (
+	"golox/expr"
+	"golox/stmt"
+	"golox/token"
+)
+
+// Declaration is a variable introduced by a var statement, or a builtin global.
+type Declaration struct {
+	Name token.Token
+	// Depth is the number of scopes enclosing the declaration, 0 for script level variables
+	// and -1 for builtins
+	Depth   int
+	Builtin bool
+	// Shadows is the declaration from an enclosing scope hidden by this one
+	Shadows *Declaration
+
+	Reads  []*Reference
+	Writes []*Reference
+}
+
+// Reference is a use of a variable by name.
+type Reference struct {
+	Name  token.Token
+	Write bool
+	// Declaration is nil for undefined variables
+	Declaration *Declaration
+}
+
+// Result maps every variable use of a program to its declaration.
+type Result struct {
+	Declarations []*Declaration
+	References   []*Reference
+}
+
+// Undefined returns the references to variables declared nowhere.
+func (r *Result) Undefined() []*Reference {
+	var undefined []*Reference
+	for _, ref := range r.References {
+		if ref.Declaration == nil {
+			undefined = append(undefined, ref)
+		}
+	}
+	return undefined
+}
+
+type scope map[string]*Declaration
+
+type resolver struct {
+	result *Result
+	scopes []scope
+}
+
+// Resolve binds variable uses to declarations following the scoping rules of the interpreter.
+//
+// Script level variables are visible from anywhere in the script, even before their
+// declaration, so that only names declared nowhere are reported as undefined.
+func Resolve(statements []stmt.Statement[any], builtins ...string) *Result {
+	r := &resolver{result: &Result{}}
+
+	builtinScope := scope{}
+	for _, name := range builtins {
+		builtinScope[name] = &Declaration{
+			Name:    token.NewToken(token.IDENTIFIER, name, nil, 0),
+			Depth:   -1,
+			Builtin: true,
+		}
+	}
+	r.scopes = append(r.scopes, builtinScope, scope{})
+
+	// hoist the script level declarations
+	for _, st := range statements {
+		if v, ok := st.(*stmt.VarStmt[any]); ok {
+			r.declare(v.Name)
+		}
+	}
+
+	for _, st := range statements {
+		r.stmt(st)
+	}
+	return r.result
+}
+
+func (r *resolver) stmt(st stmt.Statement[any]) {
+	if st != nil {
+		st.Accept(r)
+	}
+}
+
+func (r *resolver) expr(e expr.Expression[any]) {
+	if e != nil {
+		e.Accept(r)
+	}
+}
+
+func (r *resolver) depth() int {
+	return len(r.scopes) - 2
+}
+
+// declare adds name to the innermost scope, a redeclaration in the same scope refers to the same variable.
+func (r *resolver) declare(name token.Token) *Declaration {
+	current := r.scopes[len(r.scopes)-1]
+	if decl, ok := current[name.Lexeme]; ok {
+		return decl
+	}
+
+	decl := &Declaration{Name: name, Depth: r.depth(), Shadows: r.lookup(name.Lexeme)}
+	current[name.Lexeme] = decl
+	r.result.Declarations = append(r.result.Declarations, decl)
+	return decl
+}
+
+func (r *resolver) lookup(name string) *Declaration {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if decl, ok := r.scopes[i][name]; ok {
+			return decl
+		}
+	}
+	return nil
+}
+
+func (r *resolver) reference(name token.Token, write bool) *Reference {
+	ref := &Reference{Name: name, Write: write, Declaration: r.lookup(name.Lexeme)}
+	r.result.References = append(r.result.References, ref)
+
+	if ref.Declaration == nil {
+		return ref
+	}
+	if write {
+		ref.Declaration.Writes = append(ref.Declaration.Writes, ref)
+	} else {
+		ref.Declaration.Reads = append(ref.Declaration.Reads, ref)
+	}
+	return ref
+}
+
+// statements
+
+func (r *resolver) VisitExpressionStmt(es *stmt.ExpressionStmt[any]) {
+	r.expr(es.Expr)
+}
+
+func (r *resolver) VisitPrintStmt(ps *stmt.PrintStmt[any]) {
+	r.expr(ps.Expr)
+}
+
+func (r *resolver) VisitVarStmt(v *stmt.VarStmt[any]) {
+	// the initializer is evaluated before the variable exists, so it sees the enclosing declarations
+	r.expr(v.Initializer)
+	r.declare(v.Name)
+}
+
+func (r *resolver) VisitBlockStmt(b *stmt.BlockStmt[any]) {
+	r.scopes = append(r.scopes, scope{})
+	for _, st := range b.Stmts {
+		r.stmt(st)
+	}
+	r.scopes = r.scopes[:len(r.scopes)-1]
+}
+
+func (r *resolver) VisitIfStmt(i *stmt.IfStmt[any]) {
+	r.expr(i.Condition)
+	r.stmt(i.ThenBranch)
+	r.stmt(i.ElseBranch)
+}
+
+func (r *resolver) VisitWhileStmt(w *stmt.WhileStmt[any]) {
+	r.expr(w.Condition)
+	r.stmt(w.Body)
+}
+
+func (r *resolver) VisitTestStmt(t *stmt.TestStmt[any]) {
+	r.stmt(t.Body)
+}
+
+func (r *resolver) VisitBenchStmt(b *stmt.BenchStmt[any]) {
+	r.stmt(b.Body)
+}
+
+func (r *resolver) VisitAssertStmt(a *stmt.AssertStmt[any]) {
+	r.expr(a.Expr)
+}
+
+// expressions
+
+func (r *resolver) VisitBinary(binary *expr.Binary[any]) any {
+	r.expr(binary.Left)
+	r.expr(binary.Right)
+	return nil
+}
+
+func (r *resolver) VisitUnary(unary *expr.Unary[any]) any {
+	r.expr(unary.Right)
+	return nil
+}
+
+func (r *resolver) VisitGrouping(grouping *expr.Grouping[any]) any {
+	r.expr(grouping.Expression)
+	return nil
+}
+
+func (r *resolver) VisitLiteral(literal *expr.Literal[any]) any {
+	return nil
+}
+
+func (r *resolver) VisitVariable(variable *expr.Variable[any]) any {
+	r.reference(variable.Name, false)
+	return nil
+}
+
+func (r *resolver) VisitAssignment(assignment *expr.Assignment[any]) any {
+	r.expr(assignment.Exp)
+	r.reference(assignment.Tok, true)
+	return nil
+}
+
+// VisitCompoundAssignment records a single write reference, which also counts as a read
+// of the variable, so that the name is listed once among the references.
+func (r *resolver) VisitCompoundAssignment(assignment *expr.CompoundAssignment[any]) any {
+	r.expr(assignment.Value)
+	if ref := r.reference(assignment.Name, true); ref.Declaration != nil {
+		ref.Declaration.Reads = append(ref.Declaration.Reads, ref)
+	}
+	return nil
+}
+
+func (r *resolver) VisitLogical(logical *expr.Logical[any]) any {
+	r.expr(logical.Left)
+	r.expr(logical.Right)
+	return nil
+}
+
+func (r *resolver) VisitConditional(conditional *expr.Conditional[any]) any {
+	r.expr(conditional.Condition)
+	r.expr(conditional.Then)
+	r.expr(conditional.Else)
+	return nil
+}
+
+func (r *resolver) VisitCall(call *expr.Call[any]) any {
+	r.expr(call.Calleee)
+	for _, argument := range call.Arguments {
+		r.expr(argument)
+	}
+	return nil
+}
+
+func (r *resolver) VisitInterpolation(interpolation *expr.Interpolation[any]) any {
+	for _, part := range interpolation.Parts {
+		r.expr(part)
+	}
+	return nil
+}
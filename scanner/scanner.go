@@ -3,10 +3,18 @@ package scanner
 import (
 	"fmt"
 	"golox/errors"
+	"golox/number"
 	"golox/token"
+	"slices"
 	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
+// a byte order mark is tolerated at the start of the source
+const byteOrderMark = "\uFEFF"
+
 var keywords = map[string]token.TokenType{
 	"and":    token.AND,
 	"or":     token.OR,
@@ -25,34 +33,143 @@ var keywords = map[string]token.TokenType{
 	"if":     token.IF,
 }
 
+// escapes maps the character following a backslash in a string to the character it stands for
+var escapes = map[rune]rune{
+	'n':  '\n',
+	't':  '\t',
+	'r':  '\r',
+	'0':  0,
+	'a':  '\a',
+	'b':  '\b',
+	'f':  '\f',
+	'v':  '\v',
+	'\\': '\\',
+	'"':  '"',
+	'\'': '\'',
+	'$':  '$',
+}
+
+// Keywords returns every reserved word of the language in sorted order.
+func Keywords() []string {
+	var words []string
+	for word := range keywords {
+		words = append(words, word)
+	}
+	slices.Sort(words)
+	return words
+}
+
 type Scanner struct {
 	source string
 	tokens []token.Token
 
 	start, current, line int
+	// offset where the current line begins, columns are counted from it
+	lineStart              int
+	startLine, startColumn int
+	errors                 []errors.Diagnostic
+
+	// trivia seen since the last token
+	pending []token.Trivia
+	// the depth of braces in each string interpolation being scanned, the innermost last
+	interpolations []int
+
+	// KeepComments makes the scanner emit COMMENT tokens instead of discarding comments.
+	KeepComments bool
+	// KeepTrivia attaches whitespace, comments and unscannable text to the tokens,
+	// so that concatenating their FullText gives back the source byte for byte.
+	KeepTrivia bool
+	// Reporter is told about the scan errors as they are found, errors.DefaultReporter unless replaced
+	Reporter *errors.ErrorReporter
 }
 
 func NewScanner(source string) *Scanner {
 	return &Scanner{
-		source:  source,
-		tokens:  []token.Token{},
-		current: 0,
-		start:   0,
-		line:    1,
+		source:   source,
+		tokens:   []token.Token{},
+		current:  0,
+		start:    0,
+		line:     1,
+		Reporter: errors.DefaultReporter,
 	}
 }
 
 func (s *Scanner) ScanTokens() []token.Token {
+	if strings.HasPrefix(s.source, byteOrderMark) {
+		s.current = len(byteOrderMark)
+		s.lineStart = s.current
+		s.startLine = s.line
+		s.addTrivia(token.WHITESPACE)
+	}
+
 	for !s.isAtEnd() {
 		s.start = s.current
+		s.startLine = s.line
+		s.startColumn = s.column(s.current)
 
 		s.scanToken()
 	}
 
-	s.tokens = append(s.tokens, token.NewToken(token.EOF, "", nil, s.line))
+	s.start = s.current
+	s.startColumn = s.column(s.current)
+	if len(s.interpolations) > 0 {
+		s.error("Unterminated string interpolation.")
+	}
+	s.addToken(token.EOF)
+	if s.KeepTrivia {
+		s.splitTrivia()
+	}
 	return s.tokens
 }
 
+// splitTrivia moves the trivia following a token on its own line from the next token's leading trivia to its trailing trivia.
+func (s *Scanner) splitTrivia() {
+	for i := 1; i < len(s.tokens); i++ {
+		gap := s.tokens[i].Leading
+
+		end := len(gap)
+		for j, trivia := range gap {
+			if trivia.Kind == token.NEWLINE {
+				end = j + 1
+				break
+			}
+		}
+
+		s.tokens[i-1].Trailing = gap[:end:end]
+		s.tokens[i].Leading = gap[end:]
+	}
+}
+
+// HadError reports whether scanning this source produced any error.
+func (s *Scanner) HadError() bool {
+	return len(s.errors) > 0
+}
+
+// Errors returns the errors met while scanning, they are also reported on the default reporter.
+func (s *Scanner) Errors() []errors.Diagnostic {
+	return s.errors
+}
+
+func (s *Scanner) error(message string) {
+	s.errorAt(s.startColumn, message)
+}
+
+// errorAt reports an error spanning the current line from column up to the current character.
+func (s *Scanner) errorAt(column int, message string) {
+	s.errors = append(s.errors, errors.Diagnostic{
+		Severity: errors.SeverityError,
+		Span:     errors.Span{Line: s.line, Column: column, EndLine: s.line, EndColumn: s.column(s.current)},
+		Message:  message,
+	})
+	s.Reporter.ScanError(s.line, message)
+}
+
+// column returns the 1-based column of the character at offset on the current line,
+// columns count characters rather than bytes.
+func (s *Scanner) column(offset int) int {
+	return utf8.RuneCountInString(s.source[s.lineStart:offset]) + 1
+}
+
 func (s *Scanner) isAtEnd() bool {
 	return s.current >= len(s.source)
 }
@@ -67,29 +184,85 @@ func (s *Scanner) scanToken() {
 	case ')':
 		s.addToken(token.RIGHT_PAREN)
 	case '{':
+		if n := len(s.interpolations); n > 0 {
+			s.interpolations[n-1]++
+		}
 		s.addToken(token.LEFT_BRACE)
 	case '}':
+		n := len(s.interpolations)
+		if n > 0 && s.interpolations[n-1] == 0 {
+			// the end of an interpolated expression, the string goes on
+			s.interpolations = s.interpolations[:n-1]
+			s.stringLiteral()
+			return
+		}
+		if n > 0 {
+			s.interpolations[n-1]--
+		}
 		s.addToken(token.RIGHT_BRACE)
 	case ',':
 		s.addToken(token.COMMA)
 	case '.':
 		s.addToken(token.DOT)
 	case '-':
-		s.addToken(token.MINUS)
+		switch {
+		case s.match('-'):
+			s.addToken(token.MINUS_MINUS)
+		case s.match('='):
+			s.addToken(token.MINUS_EQUAL)
+		default:
+			s.addToken(token.MINUS)
+		}
 	case '+':
-		s.addToken(token.PLUS)
+		switch {
+		case s.match('+'):
+			s.addToken(token.PLUS_PLUS)
+		case s.match('='):
+			s.addToken(token.PLUS_EQUAL)
+		default:
+			s.addToken(token.PLUS)
+		}
 	case ';':
 		s.addToken(token.SEMICOLON)
 	case '*':
-		s.addToken(token.STAR)
+		switch {
+		case s.match('*'):
+			s.addToken(token.STAR_STAR)
+		case s.match('='):
+			s.addToken(token.STAR_EQUAL)
+		default:
+			s.addToken(token.STAR)
+		}
+	case '?':
+		s.addToken(If(s.match('?'), token.QUESTION_QUESTION, token.QUESTION))
+	case ':':
+		s.addToken(token.COLON)
+	case '%':
+		s.addToken(If(s.match('='), token.PERCENT_EQUAL, token.PERCENT))
+	case '&':
+		s.addToken(token.AMPERSAND)
+	case '|':
+		s.addToken(token.PIPE)
+	case '^':
+		s.addToken(token.CARET)
 
 		// one or two character(s) token
 	case '=':
 		s.addToken(If(s.match('='), token.EQUAL_EQUAL, token.EQUAL))
 	case '<':
-		s.addToken(If(s.match('='), token.LESS_EQUAL, token.LESS))
+		if s.match('<') {
+			s.addToken(token.LESS_LESS)
+		} else {
+			s.addToken(If(s.match('='), token.LESS_EQUAL, token.LESS))
+		}
 	case '>':
-		s.addToken(If(s.match('='), token.GREATER_EQUAL, token.GREATER))
+		if s.match('>') {
+			s.addToken(token.GREATER_GREATER)
+		} else {
+			s.addToken(If(s.match('='), token.GREATER_EQUAL, token.GREATER))
+		}
+	case '~':
+		s.addToken(If(s.match('/'), token.TILDE_SLASH, token.TILDE))
 	case '!':
 		s.addToken(If(s.match('='), token.BANG_EQUAL, token.BANG))
 	case '/':
@@ -97,51 +270,58 @@ func (s *Scanner) scanToken() {
 			for s.peek() != '\n' && !s.isAtEnd() {
 				s.advance()
 			}
+			s.addComment(token.LINE_COMMENT)
 		} else if s.match('*') {
 			for !(s.peek() == '*' && s.peekNext() == '/') && !s.isAtEnd() {
 				if s.peek() == '\n' {
 					s.line++
+					s.lineStart = s.current + 1
 				}
 
 				s.advance()
 			}
 
 			if s.isAtEnd() {
-				errors.Perror(s.line, "Unterminated comment.")
+				s.error("Unterminated comment.")
+				s.addTrivia(token.SKIPPED)
 				return
 			}
 			s.advance()
 			s.advance()
+			s.addComment(token.BLOCK_COMMENT)
 		} else {
-			s.addToken(token.SLASH)
+			s.addToken(If(s.match('='), token.SLASH_EQUAL, token.SLASH))
 		}
 
 		// character that don't have any sens
-	case ' ':
-	case '\t':
-	case '\r':
-		break
+	case ' ', '\t', '\r':
+		s.addTrivia(token.WHITESPACE)
 	case '\n':
+		s.addTrivia(token.NEWLINE)
 		s.line++
+		s.lineStart = s.current
 
 		// long character (string)
 	case '"':
 		s.stringLiteral()
+	case '`':
+		s.rawString()
 
 	default:
 		if isDigit(char) {
 			s.number()
-		} else if isAlpha(char) {
+		} else if IsIdentifierStart(char) {
 			s.identifier()
 		} else {
-			errors.Perror(s.line, "Unexpected character.")
+			s.error("Unexpected character.")
+			s.addTrivia(token.SKIPPED)
 		}
 	}
 }
 
 func (s *Scanner) identifier() {
 
-	for isAlphaNumberic(s.peek()) {
+	for IsIdentifierContinue(s.peek()) {
 		s.advance()
 	}
 
@@ -153,71 +333,307 @@ func (s *Scanner) identifier() {
 	s.addToken(tokenType)
 }
 
-func isAlphaNumberic(char byte) bool {
-	return isDigit(char) || isAlpha(char)
+// IsIdentifierStart and IsIdentifierContinue follow the default identifiers of
+// UAX #31, with the underscore allowed anywhere. They are exported for the tools
+// that find identifiers in text that isn't scanned, like the completion of the REPL.
+func IsIdentifierStart(char rune) bool {
+	if char == '_' {
+		return true
+	}
+	return unicode.In(char, unicode.L, unicode.Nl, unicode.Other_ID_Start) &&
+		!unicode.In(char, unicode.Pattern_Syntax, unicode.Pattern_White_Space)
 }
 
-func isAlpha(char byte) bool {
-	return (char >= 'a' && char <= 'z') ||
-		(char >= 'A' && char <= 'Z') ||
-		char == '_'
+func IsIdentifierContinue(char rune) bool {
+	if IsIdentifierStart(char) {
+		return true
+	}
+	return unicode.In(char, unicode.Mn, unicode.Mc, unicode.Nd, unicode.Pc, unicode.Other_ID_Continue) &&
+		!unicode.In(char, unicode.Pattern_Syntax, unicode.Pattern_White_Space)
 }
-func isDigit(char byte) bool {
+
+// numbers are written with ASCII digits only
+func isDigit(char rune) bool {
 	return char >= '0' && char <= '9'
 }
 
+func isHexDigit(char rune) bool {
+	return isDigit(char) || (char >= 'a' && char <= 'f') || (char >= 'A' && char <= 'F')
+}
+
+// the literals starting with 0 and a base letter
+var bases = map[rune]struct {
+	base int
+	name string
+}{
+	'x': {16, "hexadecimal"},
+	'X': {16, "hexadecimal"},
+	'b': {2, "binary"},
+	'B': {2, "binary"},
+	'o': {8, "octal"},
+	'O': {8, "octal"},
+}
+
+// number scans a decimal literal, with an optional fraction, exponent and d suffix, or an
+// integer literal in another base. Underscores can separate digits anywhere in both.
 func (s *Scanner) number() {
-	for isDigit(s.peek()) {
+	if base, ok := bases[s.peek()]; ok && s.source[s.start] == '0' {
 		s.advance()
+		s.basedNumber(base.base, base.name)
+		return
 	}
 
+	// a number without a fraction or an exponent is an integer
+	integer := true
+	s.skipDigits()
 	if s.peek() == '.' && isDigit(s.peekNext()) {
+		integer = false
 		s.advance()
+		s.skipDigits()
+	}
 
-		for isDigit(s.peek()) {
+	if s.peek() == 'e' || s.peek() == 'E' {
+		integer = false
+		s.advance()
+		if s.peek() == '+' || s.peek() == '-' {
 			s.advance()
 		}
+		if !isDigit(s.peek()) {
+			s.numberError("Expected digits in the exponent.")
+			return
+		}
+		s.skipDigits()
+	}
+
+	// a d suffix makes a decimal, 12.34d
+	decimal := s.peek() == 'd' && !IsIdentifierContinue(s.peekNext())
+	if decimal {
+		s.advance()
+	}
+
+	if IsIdentifierContinue(s.peek()) {
+		s.numberError(fmt.Sprintf("Unexpected character '%c' in number.", s.peek()))
+		return
+	}
+
+	text := strings.TrimSuffix(s.source[s.start:s.current], "d")
+	if !separated(text, isDigit) {
+		s.numberError("'_' must separate digits.")
+		return
 	}
 
-	digit, err := strconv.ParseFloat(s.source[s.start:s.current], 64)
+	if decimal {
+		value, err := number.ParseDecimal(strings.ReplaceAll(text, "_", ""))
+		if err != nil {
+			s.numberError(err.Error() + ".")
+			return
+		}
+		s.addTokenLiteral(token.NUMBER, value)
+		return
+	}
+
+	if integer {
+		value, _ := number.ParseInt(strings.ReplaceAll(text, "_", ""), 10)
+		s.addTokenLiteral(token.NUMBER, value)
+		return
+	}
+
+	value, err := strconv.ParseFloat(strings.ReplaceAll(text, "_", ""), 64)
 	if err != nil {
-		errors.Perror(s.line, fmt.Sprintf("Can not convert %s to a floating point number", s.source[s.start:s.current]))
+		s.numberError("Number literal out of range.")
+		return
+	}
+	s.addTokenLiteral(token.NUMBER, value)
+}
+
+func (s *Scanner) skipDigits() {
+	for isDigit(s.peek()) || s.peek() == '_' {
+		s.advance()
+	}
+}
+
+// basedNumber scans the digits following the prefix of an integer in base.
+func (s *Scanner) basedNumber(base int, name string) {
+	start := s.current
+	for IsIdentifierContinue(s.peek()) {
+		s.advance()
+	}
+	digits := s.source[start:s.current]
+
+	isBaseDigit := func(char rune) bool {
+		value, err := strconv.ParseUint(string(char), base, 8)
+		return err == nil && int(value) < base
 	}
-	s.addTokenLiteral(token.NUMBER, digit)
+
+	if digits == "" {
+		s.numberError(fmt.Sprintf("Expected digits after '%s'.", s.source[s.start:start]))
+		return
+	}
+	for _, char := range digits {
+		if char != '_' && !isBaseDigit(char) {
+			s.numberError(fmt.Sprintf("Invalid digit '%c' in %s literal.", char, name))
+			return
+		}
+	}
+	if !separated(digits, isBaseDigit) {
+		s.numberError("'_' must separate digits.")
+		return
+	}
+
+	value, _ := number.ParseInt(strings.ReplaceAll(digits, "_", ""), base)
+	s.addTokenLiteral(token.NUMBER, value)
 }
 
-func (s *Scanner) peekNext() byte {
+// separated reports whether every underscore in text sits between two digits.
+func separated(text string, isDigit func(rune) bool) bool {
+	for i := 0; i < len(text); i++ {
+		if text[i] != '_' {
+			continue
+		}
+		if i == 0 || i == len(text)-1 || !isDigit(rune(text[i-1])) || !isDigit(rune(text[i+1])) {
+			return false
+		}
+	}
+	return true
+}
+
+// numberError reports a malformed number, the rest of it is skipped so that it isn't scanned as other tokens.
+func (s *Scanner) numberError(message string) {
+	for IsIdentifierContinue(s.peek()) {
+		s.advance()
+	}
+	s.error(message)
+	s.addTrivia(token.SKIPPED)
+}
+
+func (s *Scanner) peekNext() rune {
 	if s.isAtEnd() {
 		return '\000'
 	}
-	return s.source[s.current+1]
+	_, size := utf8.DecodeRuneInString(s.source[s.current:])
+	if s.current+size >= len(s.source) {
+		return '\000'
+	}
+	char, _ := utf8.DecodeRuneInString(s.source[s.current+size:])
+	return char
 }
 
+// stringLiteral scans a string up to its closing quote, or up to the next "${" that
+// starts an interpolated expression. The scanning of the string resumes after the '}' closing it.
 func (s *Scanner) stringLiteral() {
+	var value strings.Builder
 	for s.peek() != '"' && !s.isAtEnd() {
+		if s.peek() == '$' && s.peekNext() == '{' {
+			s.advance()
+			s.advance()
+			s.interpolations = append(s.interpolations, 0)
+			s.addTokenLiteral(token.INTERPOLATION, value.String())
+			return
+		}
+
+		char := s.advance()
+		switch char {
+		case '\\':
+			s.escape(&value)
+		case '\n':
+			s.line++
+			s.lineStart = s.current
+			value.WriteRune(char)
+		default:
+			value.WriteRune(char)
+		}
+	}
+
+	if s.isAtEnd() {
+		// inside an interpolation the end of the source is reported once, as an unterminated interpolation
+		if len(s.interpolations) == 0 {
+			s.error("Unterminated string")
+		}
+		s.addTrivia(token.SKIPPED)
+		return
+	}
+
+	s.advance()
+	s.addTokenLiteral(token.STRING, value.String())
+}
+
+// escape decodes the escape sequence following a backslash, an invalid one is
+// reported and left out of the string so that scanning goes on to the closing quote.
+func (s *Scanner) escape(value *strings.Builder) {
+	backslash := s.current - 1
+	column := s.column(backslash)
+	if s.isAtEnd() || s.peek() == '\n' {
+		s.errorAt(column, "Invalid escape sequence at the end of the line.")
+		return
+	}
+
+	if s.match('u') {
+		s.unicodeEscape(value, column)
+		return
+	}
+
+	if escaped, ok := escapes[s.peek()]; ok {
+		s.advance()
+		value.WriteRune(escaped)
+		return
+	}
+
+	s.advance()
+	s.errorAt(column, fmt.Sprintf("Invalid escape sequence '%s'.", s.source[backslash:s.current]))
+}
+
+// unicodeEscape decodes the code point of a \u{X} escape with 1 to 6 hex digits, or a \uXXXX one.
+func (s *Scanner) unicodeEscape(value *strings.Builder, column int) {
+	braced := s.match('{')
+	start := s.current
+	for isHexDigit(s.peek()) && (braced || s.current-start < 4) {
+		s.advance()
+	}
+	digits := s.source[start:s.current]
+
+	if braced && (!s.match('}') || len(digits) == 0 || len(digits) > 6) || !braced && len(digits) != 4 {
+		s.errorAt(column, "Invalid Unicode escape, expected \\u{X} with 1 to 6 hex digits or \\uXXXX.")
+		return
+	}
+
+	code, _ := strconv.ParseUint(digits, 16, 32)
+	if !utf8.ValidRune(rune(code)) {
+		s.errorAt(column, fmt.Sprintf("Invalid Unicode code point U+%04X.", code))
+		return
+	}
+	value.WriteRune(rune(code))
+}
+
+// rawString scans a backtick string, which has no escape sequences and keeps every character as written.
+func (s *Scanner) rawString() {
+	for s.peek() != '`' && !s.isAtEnd() {
 		if s.peek() == '\n' {
 			s.line++
+			s.lineStart = s.current + 1
 		}
 		s.advance()
 	}
 
 	if s.isAtEnd() {
-		errors.Perror(s.line, "Unterminated string")
+		// as in stringLiteral, an open interpolation is the error to report
+		if len(s.interpolations) == 0 {
+			s.error("Unterminated raw string.")
+		}
+		s.addTrivia(token.SKIPPED)
 		return
 	}
 
 	s.advance()
-
-	text := s.source[s.start+1 : s.current-1]
-	s.addTokenLiteral(token.STRING, text)
+	s.addTokenLiteral(token.STRING, s.source[s.start+1:s.current-1])
 }
 
-func (s *Scanner) peek() byte {
+func (s *Scanner) peek() rune {
 	if s.isAtEnd() {
 		return '\000'
 	}
 
-	return s.source[s.current]
+	char, _ := utf8.DecodeRuneInString(s.source[s.current:])
+	return char
 }
 
 func If[T any](condition bool, a, b T) T {
@@ -227,30 +643,58 @@ func If[T any](condition bool, a, b T) T {
 	return b
 }
 
-func (s *Scanner) match(char byte) bool {
+func (s *Scanner) match(char rune) bool {
 	if s.isAtEnd() {
 		return false
 	}
 
-	if s.source[s.current] != char {
+	if s.peek() != char {
 		return false
 	}
 
-	s.current++
+	s.current += utf8.RuneLen(char)
 	return true
 }
 
-func (s *Scanner) advance() byte {
-	char := s.source[s.current]
-	s.current++
+// advance consumes the next character, the bytes of invalid UTF-8 are consumed one at a time as utf8.RuneError.
+func (s *Scanner) advance() rune {
+	char, size := utf8.DecodeRuneInString(s.source[s.current:])
+	s.current += size
 	return char
 }
 
+func (s *Scanner) addComment(kind token.TriviaKind) {
+	if s.KeepTrivia {
+		s.addTrivia(kind)
+	} else if s.KeepComments {
+		s.addToken(token.COMMENT)
+	}
+}
+
+// addTrivia records the current lexeme as trivia, merging runs of whitespace.
+func (s *Scanner) addTrivia(kind token.TriviaKind) {
+	if !s.KeepTrivia {
+		return
+	}
+
+	text := s.source[s.start:s.current]
+	if last := len(s.pending) - 1; kind == token.WHITESPACE && last >= 0 && s.pending[last].Kind == token.WHITESPACE {
+		s.pending[last].Text += text
+		return
+	}
+	s.pending = append(s.pending, token.Trivia{Kind: kind, Text: text, Line: s.startLine})
+}
+
 func (s *Scanner) addToken(tokenType token.TokenType) {
 	s.addTokenLiteral(tokenType, nil)
 }
 
 func (s *Scanner) addTokenLiteral(tokenType token.TokenType, literal any) {
 	text := s.source[s.start:s.current]
-	s.tokens = append(s.tokens, token.NewToken(tokenType, text, literal, s.line))
+	tok := token.NewToken(tokenType, text, literal, s.line)
+	tok.Column = s.startColumn
+	if s.KeepTrivia {
+		tok.Leading, s.pending = s.pending, nil
+	}
+	s.tokens = append(s.tokens, tok)
 }
@@ -0,0 +1,299 @@
+package scanner
+
+import (
+	"fmt"
+	"golox/errors"
+	"golox/number"
+	"golox/token"
+	"io"
+	"math"
+	"math/big"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func scanTrivia(source string) []token.Token {
+	s := NewScanner(source)
+	s.KeepTrivia = true
+	return s.ScanTokens()
+}
+
+func TestTriviaIsLossless(t *testing.T) {
+	sources := []string{
+		"",
+		"print 1;",
+		"  // only a comment",
+		"var a = 1;   // trailing\r\n\n\t/* block\n comment */ print a ;\n",
+		"{\n  var a = \"multi\nline\";\n}\n\n",
+		"var b = 1 @ 2; \"unterminated",
+		"1 /* unterminated *",
+		"print \"a ${b + \"c ${d}\"} e\";",
+	}
+
+	for _, source := range sources {
+		var builder strings.Builder
+		for _, tok := range scanTrivia(source) {
+			builder.WriteString(tok.FullText())
+		}
+
+		if got := builder.String(); got != source {
+			t.Errorf("got %q, want %q", got, source)
+		}
+	}
+}
+
+func TestTriviaAttachment(t *testing.T) {
+	tokens := scanTrivia("var a; // note\n\n// doc\nprint a;")
+
+	semicolon := tokens[2]
+	if len(semicolon.Trailing) != 3 || semicolon.Trailing[1].Kind != token.LINE_COMMENT {
+		t.Errorf("expected the trailing comment and newline on ';', got %+v", semicolon.Trailing)
+	}
+
+	print := tokens[3]
+	if print.TokenType != token.PRINT {
+		t.Fatalf("expected PRINT, got %s", print.TokenType)
+	}
+	kinds := []token.TriviaKind{}
+	for _, trivia := range print.Leading {
+		kinds = append(kinds, trivia.Kind)
+	}
+	want := []token.TriviaKind{token.NEWLINE, token.LINE_COMMENT, token.NEWLINE}
+	if len(kinds) != len(want) || kinds[0] != want[0] || kinds[1] != want[1] || kinds[2] != want[2] {
+		t.Errorf("got leading trivia %v, want %v", kinds, want)
+	}
+	if print.Leading[1].Line != 3 {
+		t.Errorf("got comment on line %d, want 3", print.Leading[1].Line)
+	}
+}
+
+func TestTriviaIsOptIn(t *testing.T) {
+	for _, tok := range NewScanner("var a; // note\n").ScanTokens() {
+		if tok.Leading != nil || tok.Trailing != nil {
+			t.Errorf("unexpected trivia on %s", tok.String())
+		}
+	}
+}
+
+func TestStringEscapes(t *testing.T) {
+	tests := []struct {
+		source, want string
+	}{
+		{`"a\tb\nc"`, "a\tb\nc"},
+		{`"\"quoted\" \\ \'"`, `"quoted" \ '`},
+		{`"\0\a\b\f\v\r"`, "\x00\a\b\f\v\r"},
+		{`"caf\u00e9 \u{1F600}"`, "café 😀"},
+		{"`raw \\n \\u{41}\nline`", "raw \\n \\u{41}\nline"},
+	}
+
+	for _, test := range tests {
+		s := NewScanner(test.source)
+		tokens := s.ScanTokens()
+		if s.HadError() {
+			t.Errorf("%s: unexpected errors %v", test.source, s.Errors())
+			continue
+		}
+		if got := tokens[0].Literal; got != test.want {
+			t.Errorf("%s: got %q, want %q", test.source, got, test.want)
+		}
+	}
+}
+
+func TestInvalidEscapes(t *testing.T) {
+	tests := []struct {
+		source, message string
+		column          int
+	}{
+		{`"ab\q"`, `Invalid escape sequence '\q'.`, 4},
+		{`"\é"`, `Invalid escape sequence '\é'.`, 2},
+		{`"\u12"`, `Invalid Unicode escape, expected \u{X} with 1 to 6 hex digits or \uXXXX.`, 2},
+		{`"\u{}"`, `Invalid Unicode escape, expected \u{X} with 1 to 6 hex digits or \uXXXX.`, 2},
+		{`"\u{1234567}"`, `Invalid Unicode escape, expected \u{X} with 1 to 6 hex digits or \uXXXX.`, 2},
+		{`"\uD800"`, `Invalid Unicode code point U+D800.`, 2},
+		{"\"x\\\n\"", `Invalid escape sequence at the end of the line.`, 3},
+		{"`never closed", `Unterminated raw string.`, 1},
+	}
+
+	for _, test := range tests {
+		s := NewScanner(test.source)
+		s.Reporter = errors.NewErrorReporter(io.Discard)
+		s.ScanTokens()
+
+		errs := s.Errors()
+		if len(errs) != 1 || errs[0].Message != test.message || errs[0].Span.Column != test.column {
+			t.Errorf("%s: got errors %+v, want %q at column %d", test.source, errs, test.message, test.column)
+		}
+	}
+}
+
+func TestUnicodeIdentifiers(t *testing.T) {
+	tokens := NewScanner("var café = naïve_1 + Δx + 変数 + x́;").ScanTokens()
+
+	var identifiers []string
+	for _, tok := range tokens {
+		if tok.TokenType == token.IDENTIFIER {
+			identifiers = append(identifiers, tok.Lexeme)
+		}
+	}
+	want := []string{"café", "naïve_1", "Δx", "変数", "x́"}
+	if !slices.Equal(identifiers, want) {
+		t.Errorf("got identifiers %q, want %q", identifiers, want)
+	}
+}
+
+func TestColumnsCountCharacters(t *testing.T) {
+	s := NewScanner("var é = \"ü\"; @")
+	s.Reporter = errors.NewErrorReporter(io.Discard)
+	tokens := s.ScanTokens()
+
+	var columns []int
+	for _, tok := range tokens {
+		columns = append(columns, tok.Column)
+	}
+	if want := []int{1, 5, 7, 9, 12, 15}; !slices.Equal(columns, want) {
+		t.Errorf("got columns %v, want %v", columns, want)
+	}
+
+	errs := s.Errors()
+	if len(errs) != 1 || errs[0].Span.Column != 14 || errs[0].Span.EndColumn != 15 {
+		t.Errorf("got errors %+v, want one at column 14", errs)
+	}
+}
+
+func TestByteOrderMark(t *testing.T) {
+	source := "\uFEFFprint 1;"
+	s := NewScanner(source)
+	tokens := s.ScanTokens()
+	if s.HadError() || tokens[0].TokenType != token.PRINT || tokens[0].Column != 1 {
+		t.Fatalf("got %+v, errors %v", tokens[0], s.Errors())
+	}
+
+	trivia := scanTrivia(source)
+	var builder strings.Builder
+	for _, tok := range trivia {
+		builder.WriteString(tok.FullText())
+	}
+	if builder.String() != source {
+		t.Errorf("got %q, want the byte order mark kept as trivia", builder.String())
+	}
+	if leading := trivia[0].Leading; len(leading) != 1 || leading[0].Line != 1 {
+		t.Errorf("got leading trivia %+v, want the byte order mark on line 1", leading)
+	}
+}
+
+func TestInterpolation(t *testing.T) {
+	tokens := NewScanner(`"a ${b + "c ${d}"} e${f}"`).ScanTokens()
+
+	var got []string
+	for _, tok := range tokens {
+		got = append(got, fmt.Sprintf("%s %s %v", tok.TokenType, tok.Lexeme, tok.Literal))
+	}
+	want := []string{
+		`INTERPOLATION "a ${ a `,
+		"IDENTIFIER b <nil>",
+		"PLUS + <nil>",
+		`INTERPOLATION "c ${ c `,
+		"IDENTIFIER d <nil>",
+		`STRING }" `,
+		"INTERPOLATION } e${  e",
+		"IDENTIFIER f <nil>",
+		`STRING }" `,
+		"EOF  <nil>",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got tokens\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestUnterminatedInterpolation(t *testing.T) {
+	for _, source := range []string{`"${1`, `"${"a}`, "\"${`a}"} {
+		s := NewScanner(source)
+		s.Reporter = errors.NewErrorReporter(io.Discard)
+		s.ScanTokens()
+
+		errs := s.Errors()
+		if len(errs) != 1 || errs[0].Message != "Unterminated string interpolation." {
+			t.Errorf("%s: got errors %+v, want only the unterminated interpolation", source, errs)
+		}
+	}
+}
+
+func TestNumberLiterals(t *testing.T) {
+	huge, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	decimal := func(text string) number.Decimal {
+		d, _ := number.ParseDecimal(text)
+		return d
+	}
+
+	tests := []struct {
+		source string
+		want   any
+	}{
+		{"123", int64(123)},
+		{"123.45", 123.45},
+		{"1_000_000", int64(1000000)},
+		{"0xFF", int64(255)},
+		{"0Xdead_beef", int64(0xdeadbeef)},
+		{"0b1010", int64(10)},
+		{"0o755", int64(0o755)},
+		{"1e-9", 1e-9},
+		{"6.02E23", 6.02e23},
+		{"2e+3", 2000.0},
+		{"007", int64(7)},
+		{"9223372036854775807", int64(math.MaxInt64)},
+		{"123456789012345678901234567890", huge},
+		{"12.34d", decimal("12.34")},
+		{"1_000.50d", decimal("1000.50")},
+		{"5d", decimal("5")},
+		{"1.5e3d", decimal("1500")},
+	}
+
+	for _, test := range tests {
+		s := NewScanner(test.source)
+		tokens := s.ScanTokens()
+		if s.HadError() || len(tokens) != 2 {
+			t.Errorf("%s: got tokens %v, errors %v", test.source, tokens, s.Errors())
+			continue
+		}
+		got := tokens[0].Literal
+		if tokens[0].TokenType != token.NUMBER || reflect.TypeOf(got) != reflect.TypeOf(test.want) || !number.Equal(got, test.want) {
+			t.Errorf("%s: got %T %v, want %T %v", test.source, got, got, test.want, test.want)
+		}
+	}
+}
+
+func TestMalformedNumbers(t *testing.T) {
+	tests := []struct {
+		source, message string
+	}{
+		{"0x", "Expected digits after '0x'."},
+		{"0b102", "Invalid digit '2' in binary literal."},
+		{"0o8", "Invalid digit '8' in octal literal."},
+		{"0xFG", "Invalid digit 'G' in hexadecimal literal."},
+		{"1_", "'_' must separate digits."},
+		{"1__000", "'_' must separate digits."},
+		{"0x_FF", "'_' must separate digits."},
+		{"1_.5", "'_' must separate digits."},
+		{"1e", "Expected digits in the exponent."},
+		{"1e+", "Expected digits in the exponent."},
+		{"12abc", "Unexpected character 'a' in number."},
+		{"1e400", "Number literal out of range."},
+	}
+
+	for _, test := range tests {
+		s := NewScanner(test.source + ";")
+		s.Reporter = errors.NewErrorReporter(io.Discard)
+		tokens := s.ScanTokens()
+
+		errs := s.Errors()
+		if len(errs) != 1 || errs[0].Message != test.message {
+			t.Errorf("%s: got errors %+v, want %q", test.source, errs, test.message)
+		}
+		// the malformed number is skipped as a whole
+		if len(tokens) != 2 || tokens[0].TokenType != token.SEMICOLON {
+			t.Errorf("%s: got tokens %v", test.source, tokens)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Package stepper decides where a debugged program stops, it is the state the
+// command line and the DAP debuggers share.
+package stepper
+
+import "golox/stmt"
+
+// Mode is how a program proceeds after a stop.
+type Mode int
+
+const (
+	// run until a breakpoint
+	Continue Mode = iota
+	// stop at the next statement, wherever it is
+	StepIn
+	// stop at the next statement of the same call or of a caller
+	StepOver
+	// stop at the next statement of a caller
+	StepOut
+)
+
+// Stepper decides where a program may stop. A stop is once per line: after the program
+// stopped, the other statements starting on the same line run without stopping,
+// a for loop header for instance. It stops there again when a statement that
+// already ran on the line runs anew, the next iteration of a loop.
+type Stepper struct {
+	mode Mode
+	// the call depth the step started from
+	depth int
+
+	stoppedLine int
+	// the statements that ran on the stopped line since the stop
+	ran map[stmt.Statement[any]]bool
+
+	lines map[stmt.Statement[any]]int
+}
+
+func New() *Stepper {
+	return &Stepper{lines: map[stmt.Statement[any]]int{}}
+}
+
+// Visit returns the line st starts on and whether the program may stop before it.
+// A block never stops, it starts on the line of its first statement.
+func (s *Stepper) Visit(st stmt.Statement[any]) (line int, ok bool) {
+	if _, isBlock := st.(*stmt.BlockStmt[any]); isBlock {
+		return 0, false
+	}
+
+	line = s.startLine(st)
+	if s.stoppedLine == 0 || line != s.stoppedLine || s.ran[st] {
+		s.stoppedLine, s.ran = 0, nil
+		return line, true
+	}
+	s.ran[st] = true
+	return line, false
+}
+
+// Stepping reports whether the step in progress stops before a statement run at depth calls.
+func (s *Stepper) Stepping(depth int) bool {
+	switch s.mode {
+	case StepIn:
+		return true
+	case StepOver:
+		return depth <= s.depth
+	case StepOut:
+		return depth < s.depth
+	}
+	return false
+}
+
+// Stop records that the program stopped on line, before st. st is nil when the
+// program stopped in the middle of a statement, on a watchpoint for instance.
+func (s *Stepper) Stop(st stmt.Statement[any], line int) {
+	s.stoppedLine, s.ran = line, map[stmt.Statement[any]]bool{}
+	if st != nil {
+		s.ran[st] = true
+	}
+}
+
+// Resume restarts the program in mode from a stop at depth calls.
+func (s *Stepper) Resume(mode Mode, depth int) {
+	s.mode, s.depth = mode, depth
+}
+
+// Reset forgets the stops and steps of a previous run.
+func (s *Stepper) Reset() {
+	s.mode, s.depth, s.stoppedLine, s.ran = Continue, 0, 0, nil
+}
+
+// startLine returns the line a statement starts on.
+func (s *Stepper) startLine(st stmt.Statement[any]) int {
+	line, ok := s.lines[st]
+	if !ok {
+		line, _ = stmt.Lines(st)
+		s.lines[st] = line
+	}
+	return line
+}
@@ -13,6 +13,9 @@ type Visitor[T any] interface {
 	VisitBlockStmt(b *BlockStmt[T])
 	VisitIfStmt(i *IfStmt[T])
 	VisitWhileStmt(w *WhileStmt[T])
+	VisitTestStmt(t *TestStmt[T])
+	VisitAssertStmt(a *AssertStmt[T])
+	VisitBenchStmt(b *BenchStmt[T])
 }
 
 // statement class
@@ -20,14 +23,16 @@ type Statement[T any] interface {
 	Accept(visitor Visitor[T])
 }
 
-// if statement
+// while statement, for loops are desugared into it and keep their "for" keyword
 type WhileStmt[T any] struct {
+	Keyword   token.Token
 	Condition expr.Expression[T]
 	Body      Statement[T]
 }
 
-func NewWhileStmt[T any](condition expr.Expression[T], body Statement[T]) *WhileStmt[T] {
+func NewWhileStmt[T any](keyword token.Token, condition expr.Expression[T], body Statement[T]) *WhileStmt[T] {
 	return &WhileStmt[T]{
+		Keyword:   keyword,
 		Condition: condition,
 		Body:      body,
 	}
@@ -37,14 +42,17 @@ func (w *WhileStmt[T]) Accept(visitor Visitor[T]) {
 	visitor.VisitWhileStmt(w)
 }
 
+// if statement
 type IfStmt[T any] struct {
+	Keyword    token.Token
 	Condition  expr.Expression[T]
 	ThenBranch Statement[T]
 	ElseBranch Statement[T]
 }
 
-func NewIfStmt[T any](condition expr.Expression[T], thenBranch, elseBranch Statement[T]) *IfStmt[T] {
+func NewIfStmt[T any](keyword token.Token, condition expr.Expression[T], thenBranch, elseBranch Statement[T]) *IfStmt[T] {
 	return &IfStmt[T]{
+		Keyword:    keyword,
 		Condition:  condition,
 		ThenBranch: thenBranch,
 		ElseBranch: elseBranch,
@@ -71,12 +79,14 @@ func (es *ExpressionStmt[T]) Accept(visitor Visitor[T]) {
 
 // print statement class
 type PrintStmt[T any] struct {
-	Expr expr.Expression[T]
+	Keyword token.Token
+	Expr    expr.Expression[T]
 }
 
-func NewPrintStmt[T any](e expr.Expression[T]) *PrintStmt[T] {
+func NewPrintStmt[T any](keyword token.Token, e expr.Expression[T]) *PrintStmt[T] {
 	return &PrintStmt[T]{
-		Expr: e,
+		Keyword: keyword,
+		Expr:    e,
 	}
 }
 func (es *PrintStmt[T]) Accept(visitor Visitor[T]) {
@@ -113,3 +123,63 @@ func NewBlockStmt[T any](stmts []Statement[T]) *BlockStmt[T] {
 func (b *BlockStmt[T]) Accept(visitor Visitor[T]) {
 	visitor.VisitBlockStmt(b)
 }
+
+// test declaration, ignored when the script runs and executed by the test runner
+type TestStmt[T any] struct {
+	// Keyword is the "test" identifier, it is only a keyword in front of a test name
+	Keyword token.Token
+	Name    string
+	Body    *BlockStmt[T]
+}
+
+func NewTestStmt[T any](keyword token.Token, name string, body *BlockStmt[T]) *TestStmt[T] {
+	return &TestStmt[T]{
+		Keyword: keyword,
+		Name:    name,
+		Body:    body,
+	}
+}
+
+func (t *TestStmt[T]) Accept(visitor Visitor[T]) {
+	visitor.VisitTestStmt(t)
+}
+
+// assert statement, Source is the text of the asserted expression for the failure message
+type AssertStmt[T any] struct {
+	// Keyword is the "assert" identifier, it is only a keyword in front of an operand
+	Keyword token.Token
+	Expr    expr.Expression[T]
+	Source  string
+}
+
+func NewAssertStmt[T any](keyword token.Token, e expr.Expression[T], source string) *AssertStmt[T] {
+	return &AssertStmt[T]{
+		Keyword: keyword,
+		Expr:    e,
+		Source:  source,
+	}
+}
+
+func (a *AssertStmt[T]) Accept(visitor Visitor[T]) {
+	visitor.VisitAssertStmt(a)
+}
+
+// benchmark declaration, ignored when the script runs and timed by golox bench
+type BenchStmt[T any] struct {
+	// Keyword is the "bench" identifier, it is only a keyword in front of a benchmark name
+	Keyword token.Token
+	Name    string
+	Body    *BlockStmt[T]
+}
+
+func NewBenchStmt[T any](keyword token.Token, name string, body *BlockStmt[T]) *BenchStmt[T] {
+	return &BenchStmt[T]{
+		Keyword: keyword,
+		Name:    name,
+		Body:    body,
+	}
+}
+
+func (b *BenchStmt[T]) Accept(visitor Visitor[T]) {
+	visitor.VisitBenchStmt(b)
+}
@@ -0,0 +1,94 @@
+package stmt
+
+import (
+	"golox/expr"
+	"golox/token"
+)
+
+// Lines returns the first and last source lines spanned by the tokens of st,
+// both are 0 when st holds no token.
+func Lines[T any](st Statement[T]) (first, last int) {
+	finder := &lineFinder[T]{}
+	finder.visit(st)
+	return finder.first, finder.last
+}
+
+type lineFinder[T any] struct {
+	first, last int
+}
+
+func (f *lineFinder[T]) visit(st Statement[T]) {
+	if st != nil {
+		st.Accept(f)
+	}
+}
+
+func (f *lineFinder[T]) lines(first, last int) {
+	if first == 0 {
+		return
+	}
+	if f.first == 0 || first < f.first {
+		f.first = first
+	}
+	if last > f.last {
+		f.last = last
+	}
+}
+
+func (f *lineFinder[T]) token(tok token.Token) {
+	f.lines(tok.Line, tok.Line)
+}
+
+func (f *lineFinder[T]) expr(e expr.Expression[T]) {
+	if e != nil {
+		f.lines(expr.Lines(e))
+	}
+}
+
+func (f *lineFinder[T]) VisitExpressionStmt(es *ExpressionStmt[T]) {
+	f.expr(es.Expr)
+}
+
+func (f *lineFinder[T]) VisitPrintStmt(ps *PrintStmt[T]) {
+	f.token(ps.Keyword)
+	f.expr(ps.Expr)
+}
+
+func (f *lineFinder[T]) VisitVarStmt(v *VarStmt[T]) {
+	f.token(v.Name)
+	f.expr(v.Initializer)
+}
+
+func (f *lineFinder[T]) VisitBlockStmt(b *BlockStmt[T]) {
+	for _, st := range b.Stmts {
+		f.visit(st)
+	}
+}
+
+func (f *lineFinder[T]) VisitIfStmt(i *IfStmt[T]) {
+	f.token(i.Keyword)
+	f.expr(i.Condition)
+	f.visit(i.ThenBranch)
+	f.visit(i.ElseBranch)
+}
+
+func (f *lineFinder[T]) VisitWhileStmt(w *WhileStmt[T]) {
+	f.token(w.Keyword)
+	f.expr(w.Condition)
+	f.visit(w.Body)
+}
+
+func (f *lineFinder[T]) VisitTestStmt(t *TestStmt[T]) {
+	f.token(t.Keyword)
+	f.visit(t.Body)
+}
+
+func (f *lineFinder[T]) VisitAssertStmt(a *AssertStmt[T]) {
+	f.token(a.Keyword)
+	f.expr(a.Expr)
+}
+
+func (f *lineFinder[T]) VisitBenchStmt(b *BenchStmt[T]) {
+	f.token(b.Keyword)
+	f.visit(b.Body)
+}
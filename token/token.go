@@ -1,6 +1,9 @@
 package token
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type TokenType int
 
@@ -19,6 +22,12 @@ const (
 	SEMICOLON
 	SLASH
 	STAR
+	PERCENT
+	AMPERSAND
+	PIPE
+	CARET
+	QUESTION
+	COLON
 
 	// One or two character tokens.
 	BANG
@@ -27,13 +36,30 @@ const (
 	EQUAL_EQUAL
 	GREATER
 	GREATER_EQUAL
+	GREATER_GREATER
 	LESS
 	LESS_EQUAL
+	LESS_LESS
+	STAR_STAR
+	TILDE
+	// integer division, "//" starts a comment
+	TILDE_SLASH
+	MINUS_EQUAL
+	MINUS_MINUS
+	PERCENT_EQUAL
+	PLUS_EQUAL
+	PLUS_PLUS
+	SLASH_EQUAL
+	STAR_EQUAL
+	// nil-coalescing, a ?? b
+	QUESTION_QUESTION
 
 	// Literals.
 	IDENTIFIER
 	STRING
 	NUMBER
+	// the part of an interpolated string up to a "${", the part after the last "}" is a STRING
+	INTERPOLATION
 
 	// Keywords.
 	AND
@@ -52,58 +78,136 @@ const (
 	TRUE
 	VAR
 	WHILE
+
+	// Comments, only produced when the scanner is asked to keep them.
+	COMMENT
 )
 
 // generic object to represent a value in our interpreter
 type Object any
 
+type TriviaKind int
+
+const (
+	WHITESPACE TriviaKind = iota
+	NEWLINE
+	LINE_COMMENT
+	BLOCK_COMMENT
+	// source text the scanner couldn't turn into a token
+	SKIPPED
+)
+
+// Trivia is source text carrying no meaning for the parser, kept only in lossless scanning mode.
+type Trivia struct {
+	Kind TriviaKind
+	Text string
+	Line int
+}
+
 type Token struct {
 	TokenType TokenType
 	Lexeme    string
 	Line      int
-	Literal   Object
+	// Column is the 1-based position of the lexeme on the line it starts on, counted in characters
+	Column  int
+	Literal Object
+
+	// Leading holds the trivia before the token, Trailing the trivia after it up to the end of its line.
+	Leading  []Trivia
+	Trailing []Trivia
+}
+
+// FullText returns the lexeme surrounded by its trivia, exactly as it appeared in the source.
+func (t *Token) FullText() string {
+	var builder strings.Builder
+	for _, trivia := range t.Leading {
+		builder.WriteString(trivia.Text)
+	}
+	builder.WriteString(t.Lexeme)
+	for _, trivia := range t.Trailing {
+		builder.WriteString(trivia.Text)
+	}
+	return builder.String()
 }
 
 var tokenNames = map[TokenType]string{
-	EOF:           "EOF",
-	LEFT_PAREN:    "LEFT_PAREN",
-	RIGHT_PAREN:   "RIGHT_PAREN",
-	LEFT_BRACE:    "LEFT_BRACE",
-	RIGHT_BRACE:   "RIGHT_BRACE",
-	COMMA:         "COMMA",
-	DOT:           "DOT",
-	MINUS:         "MINUS",
-	PLUS:          "PLUS",
-	SEMICOLON:     "SEMICOLON",
-	SLASH:         "SLASH",
-	STAR:          "STAR",
-	BANG:          "BANG",
-	BANG_EQUAL:    "BANG_EQUAL",
-	EQUAL:         "EQUAL",
-	EQUAL_EQUAL:   "EQUAL_EQUAL",
-	GREATER:       "GREATER",
-	GREATER_EQUAL: "GREATER_EQUAL",
-	LESS:          "LESS",
-	LESS_EQUAL:    "LESS_EQUAL",
-	IDENTIFIER:    "IDENTIFIER",
-	STRING:        "STRING",
-	NUMBER:        "NUMBER",
-	AND:           "AND",
-	CLASS:         "CLASS",
-	ELSE:          "ELSE",
-	FALSE:         "FALSE",
-	FUN:           "FUN",
-	FOR:           "FOR",
-	IF:            "IF",
-	NIL:           "NIL",
-	OR:            "OR",
-	PRINT:         "PRINT",
-	RETURN:        "RETURN",
-	SUPER:         "SUPER",
-	THIS:          "THIS",
-	TRUE:          "TRUE",
-	VAR:           "VAR",
-	WHILE:         "WHILE",
+	EOF:               "EOF",
+	LEFT_PAREN:        "LEFT_PAREN",
+	RIGHT_PAREN:       "RIGHT_PAREN",
+	LEFT_BRACE:        "LEFT_BRACE",
+	RIGHT_BRACE:       "RIGHT_BRACE",
+	COMMA:             "COMMA",
+	DOT:               "DOT",
+	MINUS:             "MINUS",
+	PLUS:              "PLUS",
+	SEMICOLON:         "SEMICOLON",
+	SLASH:             "SLASH",
+	STAR:              "STAR",
+	PERCENT:           "PERCENT",
+	AMPERSAND:         "AMPERSAND",
+	PIPE:              "PIPE",
+	CARET:             "CARET",
+	QUESTION:          "QUESTION",
+	COLON:             "COLON",
+	BANG:              "BANG",
+	BANG_EQUAL:        "BANG_EQUAL",
+	EQUAL:             "EQUAL",
+	EQUAL_EQUAL:       "EQUAL_EQUAL",
+	GREATER:           "GREATER",
+	GREATER_EQUAL:     "GREATER_EQUAL",
+	GREATER_GREATER:   "GREATER_GREATER",
+	LESS:              "LESS",
+	LESS_EQUAL:        "LESS_EQUAL",
+	LESS_LESS:         "LESS_LESS",
+	STAR_STAR:         "STAR_STAR",
+	TILDE:             "TILDE",
+	TILDE_SLASH:       "TILDE_SLASH",
+	MINUS_EQUAL:       "MINUS_EQUAL",
+	MINUS_MINUS:       "MINUS_MINUS",
+	PERCENT_EQUAL:     "PERCENT_EQUAL",
+	PLUS_EQUAL:        "PLUS_EQUAL",
+	PLUS_PLUS:         "PLUS_PLUS",
+	SLASH_EQUAL:       "SLASH_EQUAL",
+	STAR_EQUAL:        "STAR_EQUAL",
+	QUESTION_QUESTION: "QUESTION_QUESTION",
+	IDENTIFIER:        "IDENTIFIER",
+	STRING:            "STRING",
+	NUMBER:            "NUMBER",
+	INTERPOLATION:     "INTERPOLATION",
+	AND:               "AND",
+	CLASS:             "CLASS",
+	ELSE:              "ELSE",
+	FALSE:             "FALSE",
+	FUN:               "FUN",
+	FOR:               "FOR",
+	IF:                "IF",
+	NIL:               "NIL",
+	OR:                "OR",
+	PRINT:             "PRINT",
+	RETURN:            "RETURN",
+	SUPER:             "SUPER",
+	THIS:              "THIS",
+	TRUE:              "TRUE",
+	VAR:               "VAR",
+	WHILE:             "WHILE",
+	COMMENT:           "COMMENT",
+}
+
+func (t TokenType) String() string {
+	if name, ok := tokenNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("UNKNOWN(%d)", int(t))
+}
+
+// LookupType returns the token type with the given name, as printed by TokenType.String.
+func LookupType(name string) (TokenType, bool) {
+	for tokenType, tokenName := range tokenNames {
+		if tokenName == name {
+			return tokenType, true
+		}
+	}
+	return EOF, false
 }
 
 func (t *Token) String() string {
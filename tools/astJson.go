@@ -0,0 +1,611 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"golox/expr"
+	"golox/number"
+	"golox/stmt"
+	"golox/token"
+	"math/big"
+)
+
+// jsonNode is the serialized form of every expression and statement node,
+// only the fields relevant to Type are set.
+type jsonNode struct {
+	Type string `json:"type"`
+
+	Keyword  *jsonToken `json:"keyword,omitempty"`
+	Name     *jsonToken `json:"name,omitempty"`
+	Operator *jsonToken `json:"operator,omitempty"`
+	Paren    *jsonToken `json:"paren,omitempty"`
+	Value    *jsonValue `json:"value,omitempty"`
+	Source   string     `json:"source,omitempty"`
+	Postfix  bool       `json:"postfix,omitempty"`
+
+	Expression  *jsonNode   `json:"expression,omitempty"`
+	Initializer *jsonNode   `json:"initializer,omitempty"`
+	Left        *jsonNode   `json:"left,omitempty"`
+	Right       *jsonNode   `json:"right,omitempty"`
+	Callee      *jsonNode   `json:"callee,omitempty"`
+	Arguments   []*jsonNode `json:"arguments,omitempty"`
+	Condition   *jsonNode   `json:"condition,omitempty"`
+	Then        *jsonNode   `json:"then,omitempty"`
+	Else        *jsonNode   `json:"else,omitempty"`
+	Body        *jsonNode   `json:"body,omitempty"`
+	Statements  []*jsonNode `json:"statements,omitempty"`
+
+	// the first and last tokens of an interpolated string and its parts
+	Start *jsonToken  `json:"start,omitempty"`
+	End   *jsonToken  `json:"end,omitempty"`
+	Parts []*jsonNode `json:"parts,omitempty"`
+}
+
+type jsonToken struct {
+	Type   string `json:"type"`
+	Lexeme string `json:"lexeme"`
+	Line   int    `json:"line"`
+}
+
+// literal values are tagged with their kind so nil and the other types survive the round trip
+type jsonValue struct {
+	Kind  string `json:"kind"`
+	Value any    `json:"value,omitempty"`
+}
+
+// EncodeJSON serializes a parsed program to JSON.
+func EncodeJSON(statements []stmt.Statement[any]) (data []byte, err error) {
+	// literals of unknown types abort the visit with a panic
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok {
+				err = e
+				return
+			}
+			panic(r)
+		}
+	}()
+
+	encoder := &jsonEncoder{}
+	nodes := []*jsonNode{}
+	for _, st := range statements {
+		nodes = append(nodes, encoder.stmt(st))
+	}
+	return json.MarshalIndent(nodes, "", "  ")
+}
+
+type jsonEncoder struct {
+	// result of the last visited statement
+	result *jsonNode
+}
+
+func (j *jsonEncoder) stmt(st stmt.Statement[any]) *jsonNode {
+	if st == nil {
+		return nil
+	}
+	st.Accept(j)
+	return j.result
+}
+
+func (j *jsonEncoder) expr(e expr.Expression[any]) *jsonNode {
+	if e == nil {
+		return nil
+	}
+	return e.Accept(j).(*jsonNode)
+}
+
+func encodeToken(tok token.Token) *jsonToken {
+	return &jsonToken{Type: tok.TokenType.String(), Lexeme: tok.Lexeme, Line: tok.Line}
+}
+
+func encodeValue(value any) (*jsonValue, error) {
+	switch v := value.(type) {
+	case nil:
+		return &jsonValue{Kind: "nil"}, nil
+	case bool:
+		return &jsonValue{Kind: "bool", Value: v}, nil
+	case float64:
+		return &jsonValue{Kind: "number", Value: v}, nil
+	case int64, *big.Int:
+		// as a string, JSON numbers lose the digits past the precision of a float
+		return &jsonValue{Kind: "integer", Value: number.Format(v)}, nil
+	case number.Decimal:
+		return &jsonValue{Kind: "decimal", Value: v.String()}, nil
+	case string:
+		return &jsonValue{Kind: "string", Value: v}, nil
+	}
+	return nil, fmt.Errorf("can't serialize literal of type %T", value)
+}
+
+func (j *jsonEncoder) VisitExpressionStmt(es *stmt.ExpressionStmt[any]) {
+	j.result = &jsonNode{Type: "Expression", Expression: j.expr(es.Expr)}
+}
+
+func (j *jsonEncoder) VisitPrintStmt(ps *stmt.PrintStmt[any]) {
+	j.result = &jsonNode{Type: "Print", Keyword: encodeToken(ps.Keyword), Expression: j.expr(ps.Expr)}
+}
+
+func (j *jsonEncoder) VisitVarStmt(v *stmt.VarStmt[any]) {
+	j.result = &jsonNode{Type: "Var", Name: encodeToken(v.Name), Initializer: j.expr(v.Initializer)}
+}
+
+func (j *jsonEncoder) VisitBlockStmt(b *stmt.BlockStmt[any]) {
+	node := &jsonNode{Type: "Block", Statements: []*jsonNode{}}
+	for _, st := range b.Stmts {
+		node.Statements = append(node.Statements, j.stmt(st))
+	}
+	j.result = node
+}
+
+func (j *jsonEncoder) VisitIfStmt(i *stmt.IfStmt[any]) {
+	j.result = &jsonNode{
+		Type:      "If",
+		Keyword:   encodeToken(i.Keyword),
+		Condition: j.expr(i.Condition),
+		Then:      j.stmt(i.ThenBranch),
+		Else:      j.stmt(i.ElseBranch),
+	}
+}
+
+func (j *jsonEncoder) VisitWhileStmt(w *stmt.WhileStmt[any]) {
+	j.result = &jsonNode{
+		Type:      "While",
+		Keyword:   encodeToken(w.Keyword),
+		Condition: j.expr(w.Condition),
+		Body:      j.stmt(w.Body),
+	}
+}
+
+func (j *jsonEncoder) VisitTestStmt(t *stmt.TestStmt[any]) {
+	j.result = &jsonNode{
+		Type:    "Test",
+		Keyword: encodeToken(t.Keyword),
+		Value:   &jsonValue{Kind: "string", Value: t.Name},
+		Body:    j.stmt(t.Body),
+	}
+}
+
+func (j *jsonEncoder) VisitBenchStmt(b *stmt.BenchStmt[any]) {
+	j.result = &jsonNode{
+		Type:    "Bench",
+		Keyword: encodeToken(b.Keyword),
+		Value:   &jsonValue{Kind: "string", Value: b.Name},
+		Body:    j.stmt(b.Body),
+	}
+}
+
+func (j *jsonEncoder) VisitAssertStmt(a *stmt.AssertStmt[any]) {
+	j.result = &jsonNode{
+		Type:       "Assert",
+		Keyword:    encodeToken(a.Keyword),
+		Expression: j.expr(a.Expr),
+		Source:     a.Source,
+	}
+}
+
+func (j *jsonEncoder) VisitBinary(binary *expr.Binary[any]) any {
+	return &jsonNode{
+		Type:     "Binary",
+		Operator: encodeToken(*binary.Operator),
+		Left:     j.expr(binary.Left),
+		Right:    j.expr(binary.Right),
+	}
+}
+
+func (j *jsonEncoder) VisitUnary(unary *expr.Unary[any]) any {
+	return &jsonNode{Type: "Unary", Operator: encodeToken(*unary.Operator), Right: j.expr(unary.Right)}
+}
+
+func (j *jsonEncoder) VisitGrouping(grouping *expr.Grouping[any]) any {
+	return &jsonNode{Type: "Grouping", Expression: j.expr(grouping.Expression)}
+}
+
+func (j *jsonEncoder) VisitLiteral(literal *expr.Literal[any]) any {
+	value, err := encodeValue(literal.Value)
+	if err != nil {
+		panic(err)
+	}
+	return &jsonNode{Type: "Literal", Value: value}
+}
+
+func (j *jsonEncoder) VisitVariable(variable *expr.Variable[any]) any {
+	return &jsonNode{Type: "Variable", Name: encodeToken(variable.Name)}
+}
+
+func (j *jsonEncoder) VisitAssignment(assignment *expr.Assignment[any]) any {
+	return &jsonNode{Type: "Assign", Name: encodeToken(assignment.Tok), Expression: j.expr(assignment.Exp)}
+}
+
+func (j *jsonEncoder) VisitCompoundAssignment(assignment *expr.CompoundAssignment[any]) any {
+	return &jsonNode{
+		Type:       "CompoundAssign",
+		Name:       encodeToken(assignment.Name),
+		Operator:   encodeToken(assignment.Operator),
+		Expression: j.expr(assignment.Value),
+		Postfix:    assignment.Postfix,
+	}
+}
+
+func (j *jsonEncoder) VisitConditional(conditional *expr.Conditional[any]) any {
+	return &jsonNode{
+		Type:      "Conditional",
+		Operator:  encodeToken(conditional.Question),
+		Condition: j.expr(conditional.Condition),
+		Then:      j.expr(conditional.Then),
+		Else:      j.expr(conditional.Else),
+	}
+}
+
+func (j *jsonEncoder) VisitLogical(logical *expr.Logical[any]) any {
+	return &jsonNode{
+		Type:     "Logical",
+		Operator: encodeToken(logical.Operator),
+		Left:     j.expr(logical.Left),
+		Right:    j.expr(logical.Right),
+	}
+}
+
+func (j *jsonEncoder) VisitCall(call *expr.Call[any]) any {
+	node := &jsonNode{
+		Type:      "Call",
+		Callee:    j.expr(call.Calleee),
+		Paren:     encodeToken(call.OpeningParen),
+		Arguments: []*jsonNode{},
+	}
+	for _, argument := range call.Arguments {
+		node.Arguments = append(node.Arguments, j.expr(argument))
+	}
+	return node
+}
+
+func (j *jsonEncoder) VisitInterpolation(interpolation *expr.Interpolation[any]) any {
+	node := &jsonNode{
+		Type:  "Interpolation",
+		Start: encodeToken(interpolation.Start),
+		End:   encodeToken(interpolation.End),
+		Parts: []*jsonNode{},
+	}
+	for _, part := range interpolation.Parts {
+		node.Parts = append(node.Parts, j.expr(part))
+	}
+	return node
+}
+
+// DecodeJSON rebuilds an executable program from the output of EncodeJSON.
+func DecodeJSON(data []byte) ([]stmt.Statement[any], error) {
+	var nodes []*jsonNode
+	if err := json.Unmarshal(data, &nodes); err != nil {
+		return nil, err
+	}
+
+	var statements []stmt.Statement[any]
+	for _, node := range nodes {
+		st, err := decodeStmt(node)
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, st)
+	}
+	return statements, nil
+}
+
+func decodeToken(tok *jsonToken, field string) (token.Token, error) {
+	if tok == nil {
+		return token.Token{}, fmt.Errorf("missing token '%s'", field)
+	}
+
+	tokenType, ok := token.LookupType(tok.Type)
+	if !ok {
+		return token.Token{}, fmt.Errorf("unknown token type '%s'", tok.Type)
+	}
+	return token.NewToken(tokenType, tok.Lexeme, nil, tok.Line), nil
+}
+
+func decodeValue(value *jsonValue) (any, error) {
+	if value == nil {
+		return nil, fmt.Errorf("missing literal value")
+	}
+
+	switch value.Kind {
+	case "nil":
+		return nil, nil
+	case "bool":
+		if b, ok := value.Value.(bool); ok {
+			return b, nil
+		}
+	case "number":
+		if n, ok := value.Value.(float64); ok {
+			return n, nil
+		}
+	case "integer":
+		if s, ok := value.Value.(string); ok {
+			if n, ok := number.ParseInt(s, 10); ok {
+				return n, nil
+			}
+		}
+	case "decimal":
+		if s, ok := value.Value.(string); ok {
+			if d, err := number.ParseDecimal(s); err == nil {
+				return d, nil
+			}
+		}
+	case "string":
+		if s, ok := value.Value.(string); ok {
+			return s, nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown literal kind '%s'", value.Kind)
+	}
+	return nil, fmt.Errorf("invalid %s literal %v", value.Kind, value.Value)
+}
+
+func decodeStmt(node *jsonNode) (stmt.Statement[any], error) {
+	if node == nil {
+		return nil, fmt.Errorf("missing statement")
+	}
+
+	switch node.Type {
+	case "Expression":
+		e, err := decodeExpr(node.Expression)
+		if err != nil {
+			return nil, err
+		}
+		return stmt.NewExpressionStmt(e), nil
+
+	case "Print":
+		keyword, err := decodeToken(node.Keyword, "keyword")
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeExpr(node.Expression)
+		if err != nil {
+			return nil, err
+		}
+		return stmt.NewPrintStmt(keyword, e), nil
+
+	case "Var":
+		name, err := decodeToken(node.Name, "name")
+		if err != nil {
+			return nil, err
+		}
+		var initializer expr.Expression[any]
+		if node.Initializer != nil {
+			if initializer, err = decodeExpr(node.Initializer); err != nil {
+				return nil, err
+			}
+		}
+		return stmt.NewVarStmt(name, initializer), nil
+
+	case "Block":
+		var statements []stmt.Statement[any]
+		for _, child := range node.Statements {
+			st, err := decodeStmt(child)
+			if err != nil {
+				return nil, err
+			}
+			statements = append(statements, st)
+		}
+		return stmt.NewBlockStmt(statements), nil
+
+	case "If":
+		keyword, err := decodeToken(node.Keyword, "keyword")
+		if err != nil {
+			return nil, err
+		}
+		condition, err := decodeExpr(node.Condition)
+		if err != nil {
+			return nil, err
+		}
+		thenBranch, err := decodeStmt(node.Then)
+		if err != nil {
+			return nil, err
+		}
+		var elseBranch stmt.Statement[any]
+		if node.Else != nil {
+			if elseBranch, err = decodeStmt(node.Else); err != nil {
+				return nil, err
+			}
+		}
+		return stmt.NewIfStmt(keyword, condition, thenBranch, elseBranch), nil
+
+	case "While":
+		keyword, err := decodeToken(node.Keyword, "keyword")
+		if err != nil {
+			return nil, err
+		}
+		condition, err := decodeExpr(node.Condition)
+		if err != nil {
+			return nil, err
+		}
+		body, err := decodeStmt(node.Body)
+		if err != nil {
+			return nil, err
+		}
+		return stmt.NewWhileStmt(keyword, condition, body), nil
+
+	case "Test", "Bench":
+		keyword, err := decodeToken(node.Keyword, "keyword")
+		if err != nil {
+			return nil, err
+		}
+		value, err := decodeValue(node.Value)
+		if err != nil {
+			return nil, err
+		}
+		name, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s name must be a string", node.Type)
+		}
+		body, err := decodeStmt(node.Body)
+		if err != nil {
+			return nil, err
+		}
+		block, ok := body.(*stmt.BlockStmt[any])
+		if !ok {
+			return nil, fmt.Errorf("%s body must be a block", node.Type)
+		}
+		if node.Type == "Bench" {
+			return stmt.NewBenchStmt(keyword, name, block), nil
+		}
+		return stmt.NewTestStmt(keyword, name, block), nil
+
+	case "Assert":
+		keyword, err := decodeToken(node.Keyword, "keyword")
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeExpr(node.Expression)
+		if err != nil {
+			return nil, err
+		}
+		return stmt.NewAssertStmt(keyword, e, node.Source), nil
+	}
+
+	return nil, fmt.Errorf("unknown statement type '%s'", node.Type)
+}
+
+func decodeExpr(node *jsonNode) (expr.Expression[any], error) {
+	if node == nil {
+		return nil, fmt.Errorf("missing expression")
+	}
+
+	switch node.Type {
+	case "Binary", "Logical":
+		operator, err := decodeToken(node.Operator, "operator")
+		if err != nil {
+			return nil, err
+		}
+		left, err := decodeExpr(node.Left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := decodeExpr(node.Right)
+		if err != nil {
+			return nil, err
+		}
+		if node.Type == "Logical" {
+			return expr.NewLogical(left, operator, right), nil
+		}
+		return expr.NewBinary(left, operator, right), nil
+
+	case "Unary":
+		operator, err := decodeToken(node.Operator, "operator")
+		if err != nil {
+			return nil, err
+		}
+		right, err := decodeExpr(node.Right)
+		if err != nil {
+			return nil, err
+		}
+		return expr.NewUnary(operator, right), nil
+
+	case "Grouping":
+		inner, err := decodeExpr(node.Expression)
+		if err != nil {
+			return nil, err
+		}
+		return expr.NewGrouping(inner), nil
+
+	case "Literal":
+		value, err := decodeValue(node.Value)
+		if err != nil {
+			return nil, err
+		}
+		return expr.NewLiteral[any](value), nil
+
+	case "Variable":
+		name, err := decodeToken(node.Name, "name")
+		if err != nil {
+			return nil, err
+		}
+		return expr.NewVariable[any](name), nil
+
+	case "Assign":
+		name, err := decodeToken(node.Name, "name")
+		if err != nil {
+			return nil, err
+		}
+		value, err := decodeExpr(node.Expression)
+		if err != nil {
+			return nil, err
+		}
+		return expr.NewAssignment(name, value), nil
+
+	case "CompoundAssign":
+		name, err := decodeToken(node.Name, "name")
+		if err != nil {
+			return nil, err
+		}
+		operator, err := decodeToken(node.Operator, "operator")
+		if err != nil {
+			return nil, err
+		}
+		// the increments and decrements have no value
+		var value expr.Expression[any]
+		if node.Expression != nil {
+			if value, err = decodeExpr(node.Expression); err != nil {
+				return nil, err
+			}
+		}
+		return expr.NewCompoundAssignment(name, operator, value, node.Postfix), nil
+
+	case "Conditional":
+		question, err := decodeToken(node.Operator, "operator")
+		if err != nil {
+			return nil, err
+		}
+		condition, err := decodeExpr(node.Condition)
+		if err != nil {
+			return nil, err
+		}
+		then, err := decodeExpr(node.Then)
+		if err != nil {
+			return nil, err
+		}
+		otherwise, err := decodeExpr(node.Else)
+		if err != nil {
+			return nil, err
+		}
+		return expr.NewConditional(condition, question, then, otherwise), nil
+
+	case "Call":
+		callee, err := decodeExpr(node.Callee)
+		if err != nil {
+			return nil, err
+		}
+		paren, err := decodeToken(node.Paren, "paren")
+		if err != nil {
+			return nil, err
+		}
+		var arguments []expr.Expression[any]
+		for _, child := range node.Arguments {
+			argument, err := decodeExpr(child)
+			if err != nil {
+				return nil, err
+			}
+			arguments = append(arguments, argument)
+		}
+		return expr.NewCall(callee, paren, arguments), nil
+
+	case "Interpolation":
+		start, err := decodeToken(node.Start, "start")
+		if err != nil {
+			return nil, err
+		}
+		end, err := decodeToken(node.End, "end")
+		if err != nil {
+			return nil, err
+		}
+		var parts []expr.Expression[any]
+		for _, child := range node.Parts {
+			part, err := decodeExpr(child)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, part)
+		}
+		return expr.NewInterpolation(start, parts, end), nil
+	}
+
+	return nil, fmt.Errorf("unknown expression type '%s'", node.Type)
+}
@@ -0,0 +1,96 @@
+package tools
+
+import (
+	"golox/interpreter"
+	"golox/parser"
+	"golox/scanner"
+	"golox/stmt"
+	"io"
+	"os"
+	"testing"
+)
+
+// captureStdout runs fn and returns everything it printed.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout := os.Stdout
+	os.Stdout = w
+	fn()
+	os.Stdout = stdout
+	w.Close()
+
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	source := `
+var a = 0;
+var temp;
+var label = "fib";
+for (var b = 1; a < 100; b = temp + b) {
+  print a;
+  temp = a;
+  a = b;
+}
+if (!(a == nil) and true or false) print label; else print -a;
+print clock() > 0;
+assert a > 50;
+print "${label} reached ${a + 1}!";
+var n = 12345678901234567890;
+n += 1.5d;
+print n++ + --n;
+print n > 0 ? label ?? "none" : nil;
+test "fib" { assert a == 144; }
+`
+	statements, errs := parser.NewParser(scanner.NewScanner(source).ScanTokens()).Parse()
+	if errs != nil {
+		t.Fatalf("parse error: %v", errs)
+	}
+
+	data, err := EncodeJSON(statements)
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	decoded, err := DecodeJSON(data)
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+
+	printer := &AstPrinter{}
+	if got, want := printer.PrintProgram(decoded), printer.PrintProgram(statements); got != want {
+		t.Errorf("decoded tree differs:\ngot  %s\nwant %s", got, want)
+	}
+
+	run := func(program []stmt.Statement[any]) string {
+		return captureStdout(t, func() { interpreter.NewInterpreter().Interpret(program) })
+	}
+	if got, want := run(decoded), run(statements); got != want {
+		t.Errorf("decoded program printed %q, want %q", got, want)
+	}
+}
+
+func TestDecodeJSONErrors(t *testing.T) {
+	tests := []string{
+		`[{"type":"Nope"}]`,
+		`[{"type":"Print"}]`,
+		`[{"type":"Print","expression":{"type":"Literal","value":{"kind":"number","value":"1"}}}]`,
+		`[{"type":"Expression","expression":{"type":"Variable","name":{"type":"BOGUS","lexeme":"a","line":1}}}]`,
+	}
+
+	for _, data := range tests {
+		if _, err := DecodeJSON([]byte(data)); err == nil {
+			t.Errorf("expected an error decoding %s", data)
+		}
+	}
+}
@@ -3,15 +3,86 @@ package tools
 import (
 	"fmt"
 	"golox/expr"
+	"golox/number"
+	"golox/stmt"
+	"math/big"
+	"strconv"
 	"strings"
 )
 
-type AstPrinter struct{}
+// AstPrinter renders expressions and statements as S-expressions.
+type AstPrinter struct {
+	// result of the last visited statement, statement visitors don't return values
+	result string
+}
 
 func (a *AstPrinter) Print(expression expr.Expression[any]) string {
 	return expression.Accept(a).(string)
 }
 
+func (a *AstPrinter) PrintStmt(statement stmt.Statement[any]) string {
+	statement.Accept(a)
+	return a.result
+}
+
+// PrintProgram renders every top level statement on its own line.
+func (a *AstPrinter) PrintProgram(statements []stmt.Statement[any]) string {
+	var builder strings.Builder
+	for _, st := range statements {
+		builder.WriteString(a.PrintStmt(st))
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+// statements
+
+func (a *AstPrinter) VisitExpressionStmt(es *stmt.ExpressionStmt[any]) {
+	a.result = a.parenthesize(";", es.Expr)
+}
+
+func (a *AstPrinter) VisitPrintStmt(ps *stmt.PrintStmt[any]) {
+	a.result = a.parenthesize("print", ps.Expr)
+}
+
+func (a *AstPrinter) VisitVarStmt(v *stmt.VarStmt[any]) {
+	if v.Initializer == nil {
+		a.result = fmt.Sprintf("(var %s)", v.Name.Lexeme)
+		return
+	}
+	a.result = a.parenthesize("var "+v.Name.Lexeme, v.Initializer)
+}
+
+func (a *AstPrinter) VisitBlockStmt(b *stmt.BlockStmt[any]) {
+	a.result = a.parenthesizeStmts("block", b.Stmts...)
+}
+
+func (a *AstPrinter) VisitIfStmt(i *stmt.IfStmt[any]) {
+	parts := []string{"if", a.Print(i.Condition), a.PrintStmt(i.ThenBranch)}
+	if i.ElseBranch != nil {
+		parts = append(parts, a.PrintStmt(i.ElseBranch))
+	}
+	a.result = "(" + strings.Join(parts, " ") + ")"
+}
+
+func (a *AstPrinter) VisitWhileStmt(w *stmt.WhileStmt[any]) {
+	a.result = fmt.Sprintf("(while %s %s)", a.Print(w.Condition), a.PrintStmt(w.Body))
+}
+
+func (a *AstPrinter) VisitTestStmt(t *stmt.TestStmt[any]) {
+	a.result = fmt.Sprintf("(test %s %s)", strconv.Quote(t.Name), a.PrintStmt(t.Body))
+}
+
+func (a *AstPrinter) VisitBenchStmt(b *stmt.BenchStmt[any]) {
+	a.result = fmt.Sprintf("(bench %s %s)", strconv.Quote(b.Name), a.PrintStmt(b.Body))
+}
+
+func (a *AstPrinter) VisitAssertStmt(as *stmt.AssertStmt[any]) {
+	a.result = a.parenthesize("assert", as.Expr)
+}
+
+// expressions
+
 func (a *AstPrinter) VisitBinary(binary *expr.Binary[any]) any {
 	return a.parenthesize(binary.Operator.Lexeme, binary.Left, binary.Right)
 }
@@ -25,10 +96,42 @@ func (a *AstPrinter) VisitGrouping(grouping *expr.Grouping[any]) any {
 }
 
 func (a *AstPrinter) VisitLiteral(literal *expr.Literal[any]) any {
-	if literal.Value == nil {
-		return "nil"
+	return formatLiteral(literal.Value)
+}
+
+func (a *AstPrinter) VisitVariable(variable *expr.Variable[any]) any {
+	return variable.Name.Lexeme
+}
+
+func (a *AstPrinter) VisitAssignment(assignment *expr.Assignment[any]) any {
+	return a.parenthesize("= "+assignment.Tok.Lexeme, assignment.Exp)
+}
+
+// VisitCompoundAssignment prints a += 1 as (+= a 1), ++a as (++ a) and a++ as (a ++).
+func (a *AstPrinter) VisitCompoundAssignment(assignment *expr.CompoundAssignment[any]) any {
+	switch {
+	case assignment.Value != nil:
+		return a.parenthesize(assignment.Operator.Lexeme+" "+assignment.Name.Lexeme, assignment.Value)
+	case assignment.Postfix:
+		return "(" + assignment.Name.Lexeme + " " + assignment.Operator.Lexeme + ")"
 	}
-	return fmt.Sprintf("%v", literal.Value)
+	return "(" + assignment.Operator.Lexeme + " " + assignment.Name.Lexeme + ")"
+}
+
+func (a *AstPrinter) VisitLogical(logical *expr.Logical[any]) any {
+	return a.parenthesize(logical.Operator.Lexeme, logical.Left, logical.Right)
+}
+
+func (a *AstPrinter) VisitConditional(conditional *expr.Conditional[any]) any {
+	return a.parenthesize("?:", conditional.Condition, conditional.Then, conditional.Else)
+}
+
+func (a *AstPrinter) VisitCall(call *expr.Call[any]) any {
+	return a.parenthesize("call", append([]expr.Expression[any]{call.Calleee}, call.Arguments...)...)
+}
+
+func (a *AstPrinter) VisitInterpolation(interpolation *expr.Interpolation[any]) any {
+	return a.parenthesize("interpolate", interpolation.Parts...)
 }
 
 func (a *AstPrinter) parenthesize(name string, expressions ...expr.Expression[any]) string {
@@ -41,10 +144,43 @@ func (a *AstPrinter) parenthesize(name string, expressions ...expr.Expression[an
 		builder.WriteString(" ")
 		// We assert to string here because we know AstPrinter always returns strings
 		result := exp.Accept(a)
-    fmt.Fprintf(&builder, "%v", result)
+		fmt.Fprintf(&builder, "%v", result)
 	}
 
 	builder.WriteString(")")
 
 	return builder.String()
 }
+
+func (a *AstPrinter) parenthesizeStmts(name string, statements ...stmt.Statement[any]) string {
+	var builder strings.Builder
+
+	builder.WriteString("(")
+	builder.WriteString(name)
+
+	for _, st := range statements {
+		builder.WriteString(" ")
+		builder.WriteString(a.PrintStmt(st))
+	}
+
+	builder.WriteString(")")
+
+	return builder.String()
+}
+
+// formatLiteral quotes strings so they can't be mistaken for identifiers.
+func formatLiteral(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return strconv.Quote(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int64, *big.Int:
+		return number.Format(v)
+	case number.Decimal:
+		return v.String() + "d"
+	}
+	return fmt.Sprintf("%v", value)
+}
@@ -0,0 +1,46 @@
+package tools
+
+import (
+	"golox/parser"
+	"golox/scanner"
+	"testing"
+)
+
+func TestAstPrinter(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"precedence", "print 1 + 2 * 3;", "(print (+ 1 (* 2 3)))\n"},
+		{"grouping and unary", "-(1 - 2);", "(; (- (group (- 1 2))))\n"},
+		{"variables", "var a = \"x\"; a = nil;", "(var a \"x\")\n(; (= a nil))\n"},
+		{"logical and call", "clock() or false and true;", "(; (or (call clock) (and false true)))\n"},
+		{"if else", "if (a) print 1; else print 2;", "(if a (print 1) (print 2))\n"},
+		{"power", "-2 ** 3 ** 2 ** -1;", "(; (- (** 2 (** 3 (** 2 (- 1))))))\n"},
+		{"bitwise precedence", "1 | 2 ^ 3 & 4 << 1 + 1 < ~5;", "(; (< (| 1 (^ 2 (& 3 (<< 4 (+ 1 1))))) (~ 5)))\n"},
+		{"compound assignment", "a += b -= 1;", "(; (+= a (-= b 1)))\n"},
+		{"increments", "-a++ ** ++b;", "(; (- (** (a ++) (++ b))))\n"},
+		{"conditional", "a = b ? c ?? d or e : f ? 1 : 2;", "(; (= a (?: b (?? c (or d e)) (?: f 1 2))))\n"},
+		{"factor operators", "7 % 4 ~/ 2 * 1.5d;", "(; (* (~/ (% 7 4) 2) 1.5d))\n"},
+		{
+			"desugared for",
+			"for (var i = 0; i < 2; i = i + 1) print i;",
+			"(block (var i 0) (while (< i 2) (block (print i) (; (= i (+ i 1))))))\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statements, errs := parser.NewParser(scanner.NewScanner(tt.source).ScanTokens()).Parse()
+			if errs != nil {
+				t.Fatalf("parse error: %v", errs)
+			}
+
+			printer := &AstPrinter{}
+			if got := printer.PrintProgram(statements); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package tools
+
+import (
+	"fmt"
+	"golox/expr"
+	"golox/stmt"
+	"strconv"
+	"strings"
+)
+
+// TreePrinter renders the syntax tree with one node per line, children indented below their parent.
+type TreePrinter struct {
+	builder strings.Builder
+	depth   int
+}
+
+func (t *TreePrinter) PrintProgram(statements []stmt.Statement[any]) string {
+	t.builder.Reset()
+	for _, st := range statements {
+		t.stmt(st)
+	}
+	return t.builder.String()
+}
+
+func (t *TreePrinter) line(format string, args ...any) {
+	t.builder.WriteString(strings.Repeat("  ", t.depth))
+	fmt.Fprintf(&t.builder, format, args...)
+	t.builder.WriteString("\n")
+}
+
+// label prints a header line and then visits the children one level deeper.
+func (t *TreePrinter) label(name string, children func()) {
+	t.line("%s", name)
+	t.depth++
+	children()
+	t.depth--
+}
+
+func (t *TreePrinter) stmt(st stmt.Statement[any]) {
+	st.Accept(t)
+}
+
+func (t *TreePrinter) expr(e expr.Expression[any]) {
+	e.Accept(t)
+}
+
+// statements
+
+func (t *TreePrinter) VisitExpressionStmt(es *stmt.ExpressionStmt[any]) {
+	t.label("Expression", func() { t.expr(es.Expr) })
+}
+
+func (t *TreePrinter) VisitPrintStmt(ps *stmt.PrintStmt[any]) {
+	t.label("Print", func() { t.expr(ps.Expr) })
+}
+
+func (t *TreePrinter) VisitVarStmt(v *stmt.VarStmt[any]) {
+	t.label("Var "+v.Name.Lexeme, func() {
+		if v.Initializer != nil {
+			t.expr(v.Initializer)
+		}
+	})
+}
+
+func (t *TreePrinter) VisitBlockStmt(b *stmt.BlockStmt[any]) {
+	t.label("Block", func() {
+		for _, st := range b.Stmts {
+			t.stmt(st)
+		}
+	})
+}
+
+func (t *TreePrinter) VisitIfStmt(i *stmt.IfStmt[any]) {
+	t.label("If", func() {
+		t.label("condition:", func() { t.expr(i.Condition) })
+		t.label("then:", func() { t.stmt(i.ThenBranch) })
+		if i.ElseBranch != nil {
+			t.label("else:", func() { t.stmt(i.ElseBranch) })
+		}
+	})
+}
+
+func (t *TreePrinter) VisitWhileStmt(w *stmt.WhileStmt[any]) {
+	t.label("While", func() {
+		t.label("condition:", func() { t.expr(w.Condition) })
+		t.label("body:", func() { t.stmt(w.Body) })
+	})
+}
+
+func (t *TreePrinter) VisitTestStmt(test *stmt.TestStmt[any]) {
+	t.label("Test "+strconv.Quote(test.Name), func() { t.stmt(test.Body) })
+}
+
+func (t *TreePrinter) VisitBenchStmt(b *stmt.BenchStmt[any]) {
+	t.label("Bench "+strconv.Quote(b.Name), func() { t.stmt(b.Body) })
+}
+
+func (t *TreePrinter) VisitAssertStmt(a *stmt.AssertStmt[any]) {
+	t.label("Assert", func() { t.expr(a.Expr) })
+}
+
+// expressions
+
+func (t *TreePrinter) VisitBinary(binary *expr.Binary[any]) any {
+	t.label("Binary "+binary.Operator.Lexeme, func() {
+		t.expr(binary.Left)
+		t.expr(binary.Right)
+	})
+	return nil
+}
+
+func (t *TreePrinter) VisitUnary(unary *expr.Unary[any]) any {
+	t.label("Unary "+unary.Operator.Lexeme, func() { t.expr(unary.Right) })
+	return nil
+}
+
+func (t *TreePrinter) VisitGrouping(grouping *expr.Grouping[any]) any {
+	t.label("Grouping", func() { t.expr(grouping.Expression) })
+	return nil
+}
+
+func (t *TreePrinter) VisitLiteral(literal *expr.Literal[any]) any {
+	t.line("Literal %s", formatLiteral(literal.Value))
+	return nil
+}
+
+func (t *TreePrinter) VisitVariable(variable *expr.Variable[any]) any {
+	t.line("Variable %s", variable.Name.Lexeme)
+	return nil
+}
+
+func (t *TreePrinter) VisitAssignment(assignment *expr.Assignment[any]) any {
+	t.label("Assign "+assignment.Tok.Lexeme, func() { t.expr(assignment.Exp) })
+	return nil
+}
+
+func (t *TreePrinter) VisitCompoundAssignment(assignment *expr.CompoundAssignment[any]) any {
+	switch {
+	case assignment.Value != nil:
+		t.label("Assign "+assignment.Name.Lexeme+" "+assignment.Operator.Lexeme, func() { t.expr(assignment.Value) })
+	case assignment.Postfix:
+		t.line("Assign %s%s", assignment.Name.Lexeme, assignment.Operator.Lexeme)
+	default:
+		t.line("Assign %s%s", assignment.Operator.Lexeme, assignment.Name.Lexeme)
+	}
+	return nil
+}
+
+func (t *TreePrinter) VisitLogical(logical *expr.Logical[any]) any {
+	t.label("Logical "+logical.Operator.Lexeme, func() {
+		t.expr(logical.Left)
+		t.expr(logical.Right)
+	})
+	return nil
+}
+
+func (t *TreePrinter) VisitConditional(conditional *expr.Conditional[any]) any {
+	t.label("Conditional", func() {
+		t.label("condition:", func() { t.expr(conditional.Condition) })
+		t.label("then:", func() { t.expr(conditional.Then) })
+		t.label("else:", func() { t.expr(conditional.Else) })
+	})
+	return nil
+}
+
+func (t *TreePrinter) VisitInterpolation(interpolation *expr.Interpolation[any]) any {
+	t.label("Interpolation", func() {
+		for _, part := range interpolation.Parts {
+			t.expr(part)
+		}
+	})
+	return nil
+}
+
+func (t *TreePrinter) VisitCall(call *expr.Call[any]) any {
+	t.label("Call", func() {
+		t.label("callee:", func() { t.expr(call.Calleee) })
+		if len(call.Arguments) > 0 {
+			t.label("arguments:", func() {
+				for _, argument := range call.Arguments {
+					t.expr(argument)
+				}
+			})
+		}
+	})
+	return nil
+}
@@ -0,0 +1,83 @@
+// Package tracer logs the statements a program executes, for --trace.
+package tracer
+
+import (
+	"fmt"
+	"golox/interpreter"
+	"golox/stmt"
+	"golox/token"
+	"golox/tools"
+	"io"
+	"strconv"
+)
+
+// Tracer is an interpreter hook writing a line for every executed statement,
+// and one for every value stored by a declaration or an assignment.
+type Tracer struct {
+	out     io.Writer
+	printer tools.AstPrinter
+	// statements are executed many times in loops, they are described once
+	lines     map[stmt.Statement[any]]int
+	summaries map[stmt.Statement[any]]string
+}
+
+func New(out io.Writer) *Tracer {
+	return &Tracer{
+		out:       out,
+		lines:     map[stmt.Statement[any]]int{},
+		summaries: map[stmt.Statement[any]]string{},
+	}
+}
+
+func (t *Tracer) BeforeExecute(i *interpreter.Interpreter, st stmt.Statement[any]) {
+	line, ok := t.lines[st]
+	if !ok {
+		line, _ = stmt.Lines(st)
+		t.lines[st] = line
+		t.summaries[st] = t.summary(st)
+	}
+
+	fmt.Fprintf(t.out, "[line %d] depth %d: %s\n", line, depth(i), t.summaries[st])
+}
+
+func (t *Tracer) AfterDefine(i *interpreter.Interpreter, name token.Token, value any) {
+	fmt.Fprintf(t.out, "[line %d] depth %d: var %s = %s\n", name.Line, depth(i), name.Lexeme, format(i, value))
+}
+
+func (t *Tracer) AfterAssign(i *interpreter.Interpreter, name token.Token, old, value any) {
+	fmt.Fprintf(t.out, "[line %d] depth %d: %s = %s\n", name.Line, depth(i), name.Lexeme, format(i, value))
+}
+
+// summary describes a statement without the statements nested in it, they are traced on their own.
+func (t *Tracer) summary(st stmt.Statement[any]) string {
+	switch s := st.(type) {
+	case *stmt.BlockStmt[any]:
+		return "(block)"
+	case *stmt.IfStmt[any]:
+		return "(if " + t.printer.Print(s.Condition) + ")"
+	case *stmt.WhileStmt[any]:
+		return "(while " + t.printer.Print(s.Condition) + ")"
+	case *stmt.TestStmt[any]:
+		return "(test " + strconv.Quote(s.Name) + ")"
+	case *stmt.BenchStmt[any]:
+		return "(bench " + strconv.Quote(s.Name) + ")"
+	}
+	return t.printer.PrintStmt(st)
+}
+
+// depth counts the scopes between the current one and the script, which is at depth 0.
+func depth(i *interpreter.Interpreter) int {
+	depth := 0
+	for env := i.Environment(); env.Parent() != i.Globals(); env = env.Parent() {
+		depth++
+	}
+	return depth
+}
+
+// format quotes strings so that a string and the number it holds can be told apart.
+func format(i *interpreter.Interpreter, value any) string {
+	if s, ok := value.(string); ok {
+		return strconv.Quote(s)
+	}
+	return i.Stringify(value)
+}
@@ -0,0 +1,38 @@
+package tracer
+
+import (
+	"golox/interpreter"
+	"golox/parser"
+	"golox/scanner"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestTrace(t *testing.T) {
+	source := "var a = 1;\n{\n  var s = \"x\";\n  a = a + 1;\n}\nif (a > 1) print a;\n"
+	statements, errs := parser.NewParser(scanner.NewScanner(source).ScanTokens()).Parse()
+	if errs != nil {
+		t.Fatalf("parse errors: %v", errs)
+	}
+
+	var trace strings.Builder
+	interp := interpreter.NewInterpreter()
+	interp.Out = io.Discard
+	interp.Hook = New(&trace)
+	interp.Interpret(statements)
+
+	want := `[line 1] depth 0: (var a 1)
+[line 1] depth 0: var a = 1
+[line 3] depth 0: (block)
+[line 3] depth 1: (var s "x")
+[line 3] depth 1: var s = "x"
+[line 4] depth 1: (; (= a (+ a 1)))
+[line 4] depth 1: a = 2
+[line 6] depth 0: (if (> a 1))
+[line 6] depth 0: (print a)
+`
+	if trace.String() != want {
+		t.Errorf("got trace:\n%s\nwant:\n%s", trace.String(), want)
+	}
+}
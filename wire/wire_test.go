@@ -0,0 +1,20 @@
+package wire
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestRead(t *testing.T) {
+	body, err := Read(bufio.NewReader(strings.NewReader("Content-Length: 2\r\n\r\n{}")))
+	if err != nil || string(body) != "{}" {
+		t.Errorf("got %q, %v", body, err)
+	}
+
+	for _, header := range []string{"Content-Length: 1000000000000", "Content-Length: -1", "Content-Length: x", "Content-Type: json"} {
+		if _, err := Read(bufio.NewReader(strings.NewReader(header + "\r\n\r\n{}"))); err == nil {
+			t.Errorf("%q: read succeeded, want an error", header)
+		}
+	}
+}